@@ -0,0 +1,191 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultCacheTTL is how long discovered labels and values are reused
+const defaultCacheTTL = 5 * time.Minute
+
+// LabelCache keeps label discovery results in memory and on disk.
+// It is safe for concurrent use, and a nil cache never hits.
+type LabelCache struct {
+	mu      sync.Mutex
+	dir     string // Empty to keep entries in memory only
+	ttl     time.Duration
+	refresh bool // Ignore cached entries, but still store new ones
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	Values    []string  `json:"values"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// defaultCacheDir returns the on-disk cache location, or "" if there is none
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "loqui")
+}
+
+// newLabelCache creates a cache; a ttl of zero or less disables caching
+func newLabelCache(dir string, ttl time.Duration, refresh bool) *LabelCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &LabelCache{
+		dir:     dir,
+		ttl:     ttl,
+		refresh: refresh,
+		entries: map[string]cacheEntry{},
+	}
+}
+
+// cacheKey identifies a discovery result by Loki endpoint, tenant, time range,
+// what was requested and the time bucket of a relative range
+func cacheKey(endpoint, tenant string, timeArgs []string, kind string, bucket time.Time) string {
+	fields := []string{endpoint, tenant, strings.Join(timeArgs, " "), kind}
+	if !bucket.IsZero() {
+		fields = append(fields, bucket.UTC().Format(time.RFC3339))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// timeBucket returns now truncated to ttl when timeArgs are relative to now,
+// like --since 1h, so their results stop being shared once the window moved
+// by a TTL. Absolute ranges always cover the same data and get the zero time.
+func timeBucket(timeArgs []string, now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 || slices.Contains(timeArgs, "--from") {
+		return time.Time{}
+	}
+	return now.Truncate(ttl)
+}
+
+// Key returns the cache key of a discovery result fetched now
+func (c *LabelCache) Key(endpoint, tenant string, timeArgs []string, kind string) string {
+	var bucket time.Time
+	if c != nil {
+		bucket = timeBucket(timeArgs, time.Now(), c.ttl)
+	}
+	return cacheKey(endpoint, tenant, timeArgs, kind, bucket)
+}
+
+// Get returns the cached values for key if they are younger than the TTL
+func (c *LabelCache) Get(key string) ([]string, bool) {
+	if c == nil || c.refresh {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		entry, ok = c.load(key)
+		if !ok {
+			return nil, false
+		}
+		c.entries[key] = entry
+	}
+
+	if time.Since(entry.FetchedAt) > c.ttl {
+		return nil, false
+	}
+	return entry.Values, true
+}
+
+// Put stores values for key in memory and on disk
+func (c *LabelCache) Put(key string, values []string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := cacheEntry{Values: values, FetchedAt: time.Now()}
+	c.entries[key] = entry
+	c.save(key, entry)
+}
+
+// load reads an entry from disk; a missing or broken file is a cache miss
+func (c *LabelCache) load(key string) (cacheEntry, bool) {
+	if c.dir == "" {
+		return cacheEntry{}, false
+	}
+
+	data, err := os.ReadFile(filepath.Join(c.dir, key+".json"))
+	if err != nil {
+		return cacheEntry{}, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// save writes an entry to disk; the cache is best effort, so errors are ignored
+func (c *LabelCache) save(key string, entry cacheEntry) {
+	if c.dir == "" {
+		return
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return
+	}
+
+	// Write to a temporary file first so readers never see a partial entry
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if writeErr != nil || closeErr != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(c.dir, key+".json")); err != nil {
+		os.Remove(tmp.Name())
+	}
+	c.prune()
+}
+
+// prune deletes entries older than the TTL from disk. Every relative range
+// gets a new key per TTL window and every absolute range its own key, so
+// the directory would otherwise keep growing.
+func (c *LabelCache) prune() {
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || (!strings.HasSuffix(name, ".json") && !strings.HasSuffix(name, ".tmp")) {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil || time.Since(info.ModTime()) <= c.ttl {
+			continue
+		}
+		os.Remove(filepath.Join(c.dir, name))
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLabelCache(t *testing.T) {
+	dir := t.TempDir()
+	key := cacheKey("http://localhost:3100", "", []string{"--since", "1h"}, "labels", time.Time{})
+	labels := []string{"app", "env"}
+
+	cache := newLabelCache(dir, time.Minute, false)
+	if _, ok := cache.Get(key); ok {
+		t.Fatal("Get() hit on an empty cache")
+	}
+	cache.Put(key, labels)
+	if got, ok := cache.Get(key); !ok || !reflect.DeepEqual(got, labels) {
+		t.Errorf("Get() = %v, %v, want %v", got, ok, labels)
+	}
+
+	// A new cache on the same directory reads the entry from disk
+	reopened := newLabelCache(dir, time.Minute, false)
+	if got, ok := reopened.Get(key); !ok || !reflect.DeepEqual(got, labels) {
+		t.Errorf("Get() from disk = %v, %v, want %v", got, ok, labels)
+	}
+
+	// -refresh ignores cached entries
+	refreshed := newLabelCache(dir, time.Minute, true)
+	if _, ok := refreshed.Get(key); ok {
+		t.Error("Get() hit with refresh enabled")
+	}
+}
+
+func TestLabelCacheExpiry(t *testing.T) {
+	cache := newLabelCache("", time.Minute, false)
+	cache.entries["key"] = cacheEntry{Values: []string{"app"}, FetchedAt: time.Now().Add(-2 * time.Minute)}
+
+	if _, ok := cache.Get("key"); ok {
+		t.Error("Get() returned an expired entry")
+	}
+}
+
+func TestLabelCachePrune(t *testing.T) {
+	dir := t.TempDir()
+	cache := newLabelCache(dir, time.Minute, false)
+	cache.Put("old", []string{"app"})
+	cache.Put("recent", []string{"app"})
+
+	old := filepath.Join(dir, "old.json")
+	if err := os.Chtimes(old, time.Now(), time.Now().Add(-2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saving an entry deletes the expired ones
+	cache.Put("new", []string{"env"})
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("expired entry still on disk: %v", err)
+	}
+	for _, key := range []string{"recent", "new"} {
+		if _, err := os.Stat(filepath.Join(dir, key+".json")); err != nil {
+			t.Errorf("entry %s missing: %v", key, err)
+		}
+	}
+}
+
+func TestLabelCacheDisabled(t *testing.T) {
+	cache := newLabelCache(t.TempDir(), 0, false)
+	if cache != nil {
+		t.Fatal("newLabelCache() with zero TTL should disable the cache")
+	}
+
+	// A nil cache is usable and never hits
+	cache.Put("key", []string{"app"})
+	if _, ok := cache.Get("key"); ok {
+		t.Error("Get() hit on a disabled cache")
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	bucket := time.Date(2025, 8, 14, 9, 0, 0, 0, time.UTC)
+	base := cacheKey("http://loki:3100", "team-a", []string{"--since", "1h"}, "labels", bucket)
+
+	others := []string{
+		cacheKey("http://other:3100", "team-a", []string{"--since", "1h"}, "labels", bucket),
+		cacheKey("http://loki:3100", "team-b", []string{"--since", "1h"}, "labels", bucket),
+		cacheKey("http://loki:3100", "team-a", []string{"--since", "24h"}, "labels", bucket),
+		cacheKey("http://loki:3100", "team-a", []string{"--since", "1h"}, "values:app", bucket),
+		cacheKey("http://loki:3100", "team-a", []string{"--since", "1h"}, "labels", bucket.Add(5*time.Minute)),
+	}
+	for _, other := range others {
+		if other == base {
+			t.Errorf("cacheKey() collision: %s", other)
+		}
+	}
+}
+
+func TestTimeBucket(t *testing.T) {
+	now := time.Date(2025, 8, 14, 9, 7, 30, 0, time.UTC)
+	since := []string{"--since", "1h"}
+
+	want := time.Date(2025, 8, 14, 9, 5, 0, 0, time.UTC)
+	if got := timeBucket(since, now, 5*time.Minute); !got.Equal(want) {
+		t.Errorf("timeBucket() = %s, want %s", got, want)
+	}
+	// Relative ranges share a key within a TTL, not across TTLs
+	if !timeBucket(since, now, 5*time.Minute).Equal(timeBucket(since, now.Add(2*time.Minute), 5*time.Minute)) {
+		t.Error("timeBucket() differs within a TTL")
+	}
+	if timeBucket(since, now, 5*time.Minute).Equal(timeBucket(since, now.Add(5*time.Minute), 5*time.Minute)) {
+		t.Error("timeBucket() is the same across TTLs")
+	}
+
+	absolute := []string{"--from", "2025-08-14T08:00:00Z", "--to", "2025-08-14T09:00:00Z"}
+	if got := timeBucket(absolute, now, 5*time.Minute); !got.IsZero() {
+		t.Errorf("timeBucket() of an absolute range = %s, want zero", got)
+	}
+}
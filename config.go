@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FileConfig holds settings read from the configuration file
+type FileConfig struct {
+	// Selector is the fuzzy finder backend: auto, fzf, sk, peco, gum or builtin
+	Selector string `json:"selector"`
+
+	// Favorites are labels pinned to the top of the label list, in this order
+	Favorites []string `json:"favorites"`
+
+	// FavoriteValues are values pinned to the top of each label's value list
+	FavoriteValues map[string][]string `json:"favorite_values"`
+
+	// HiddenLabels are never offered, e.g. internal labels like __stream_shard__
+	HiddenLabels []string `json:"hidden_labels"`
+
+	// Cost sets when a query is expensive enough to ask for confirmation
+	Cost struct {
+		MaxBytes   string `json:"max_bytes"`   // e.g. "10GB", "-1" disables the check
+		MaxStreams int64  `json:"max_streams"` // -1 disables the check
+	} `json:"cost"`
+
+	// Trace sets the defaults of loqui trace
+	Trace struct {
+		Labels      []string `json:"labels"`       // Label names or matchers, e.g. ["namespace", "env=\"prod\""]
+		Window      string   `json:"window"`       // How far back to search, e.g. "6h"
+		MetadataKey string   `json:"metadata_key"` // Structured metadata key holding the ID
+		Match       string   `json:"match"`        // line, metadata or both
+	} `json:"trace"`
+}
+
+// defaultConfigPath returns $XDG_CONFIG_HOME/loqui/config.json (or the OS equivalent)
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "loqui", "config.json")
+}
+
+// loadFileConfig reads the configuration file at path
+// A missing file is only an error when required is set
+func loadFileConfig(path string, required bool) (*FileConfig, error) {
+	config := &FileConfig{}
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// costLimits returns the configured cost limits, using the defaults for unset ones
+func (c *FileConfig) costLimits() (CostLimits, error) {
+	limits := CostLimits{
+		MaxBytes:   defaultMaxQueryBytes,
+		MaxStreams: defaultMaxQueryStreams,
+	}
+
+	if c.Cost.MaxBytes != "" {
+		maxBytes, err := parseByteSize(c.Cost.MaxBytes)
+		if err != nil {
+			return CostLimits{}, fmt.Errorf("invalid cost.max_bytes in config file: %w", err)
+		}
+		limits.MaxBytes = maxBytes
+	}
+	if c.Cost.MaxStreams != 0 {
+		limits.MaxStreams = c.Cost.MaxStreams
+	}
+
+	return limits, nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFileConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"selector": "builtin"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := loadFileConfig(path, true)
+	if err != nil {
+		t.Fatalf("loadFileConfig() error = %v", err)
+	}
+	if config.Selector != SelectorBuiltin {
+		t.Errorf("Selector = %q, want %q", config.Selector, SelectorBuiltin)
+	}
+
+	missing := filepath.Join(dir, "missing.json")
+	if _, err := loadFileConfig(missing, false); err != nil {
+		t.Errorf("loadFileConfig() with optional missing file error = %v", err)
+	}
+	if _, err := loadFileConfig(missing, true); err == nil {
+		t.Error("expected error for a missing required config file")
+	}
+
+	broken := filepath.Join(dir, "broken.json")
+	if err := os.WriteFile(broken, []byte(`{"selector":`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadFileConfig(broken, false); err == nil {
+		t.Error("expected error for a malformed config file")
+	}
+}
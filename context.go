@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// contextWindow is how far before and after a line its context is searched
+const contextWindow = time.Hour
+
+// streamLookupWindow is how far around a line the series API looks for its stream
+const streamLookupWindow = time.Minute
+
+// entryStream returns the labels of the stream an entry comes from: the
+// series whose labels the entry all carries with the same values, the one
+// with the most labels when several do. Loki renames parsed labels clashing
+// with the stream's own, but a parsed label may still share its name with a
+// label of another stream, so names alone do not tell stream labels apart.
+func entryStream(series []map[string]string, labels map[string]string) (map[string]string, bool) {
+	var stream map[string]string
+	for _, candidate := range series {
+		matches := true
+		for name, value := range candidate {
+			if v, ok := labels[name]; !ok || v != value {
+				matches = false
+				break
+			}
+		}
+		if matches && len(candidate) > len(stream) {
+			stream = candidate
+		}
+	}
+	return stream, stream != nil
+}
+
+// contextArgs returns the logcli arguments fetching n lines of the stream
+// up to ts, or from ts on
+func contextArgs(logcliCmd string, stream []LabelSelector, ts time.Time, n int, after bool) []string {
+	// Loki includes the start and excludes the end of a range. Both windows
+	// include ts, other lines may share the timestamp of the entry.
+	timeArgs := []string{
+		"--from", ts.Add(-contextWindow).Format(time.RFC3339Nano),
+		"--to", ts.Add(time.Nanosecond).Format(time.RFC3339Nano),
+		"--limit", strconv.Itoa(n),
+	}
+	if after {
+		timeArgs = []string{
+			"--from", ts.Format(time.RFC3339Nano),
+			"--to", ts.Add(contextWindow).Format(time.RFC3339Nano),
+			"--limit", strconv.Itoa(n),
+			"--forward",
+		}
+	}
+	return buildLogCLIArgs(logcliCmd, Query{Selectors: stream}, timeArgs)
+}
+
+// entryKey identifies a line within its stream
+func entryKey(e LogEntry) string {
+	return e.Timestamp.Format(time.RFC3339Nano) + " " + e.Line
+}
+
+// fetchContext returns up to n unfiltered lines before and after the entry
+// from its stream, oldest first. Lines sharing the entry's timestamp are
+// listed once, before it.
+func fetchContext(logcliCmd string, entry LogEntry, streamLabels map[string]string, n int) ([]LogEntry, []LogEntry, error) {
+	stream := seriesSelector(streamLabels)
+	if len(stream) == 0 {
+		return nil, nil, fmt.Errorf("no stream labels found for the line")
+	}
+
+	// One more line than asked for, the entry itself is among them
+	seen := map[string]bool{entryKey(entry): true}
+	before := []LogEntry{}
+	err := streamLogCLIQuery(contextArgs(logcliCmd, stream, entry.Timestamp, n+1, false), func(e LogEntry) error {
+		if !seen[entryKey(e)] {
+			seen[entryKey(e)] = true
+			before = append(before, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	// Lines before the entry come newest first
+	before = before[:min(n, len(before))]
+	slices.Reverse(before)
+
+	// The after window starts with the lines already shown before the entry
+	sameTime := 0
+	for _, e := range before {
+		if e.Timestamp.Equal(entry.Timestamp) {
+			sameTime++
+		}
+	}
+	after := []LogEntry{}
+	err = streamLogCLIQuery(contextArgs(logcliCmd, stream, entry.Timestamp, n+1+sameTime, true), func(e LogEntry) error {
+		if !seen[entryKey(e)] && len(after) < n {
+			after = append(after, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return before, after, nil
+}
+
+// contextItems lists the entries for the selector, numbered to find them again
+func contextItems(entries []LogEntry) []string {
+	items := make([]string, len(entries))
+	for i, e := range entries {
+		line := strings.ReplaceAll(e.Line, "\n", " ")
+		items[i] = fmt.Sprintf("%d %s %s %s", i+1, e.Timestamp.Format(time.RFC3339Nano), formatLabels(e.Labels), line)
+	}
+	return items
+}
+
+// showContext lets the user pick shown lines of query and prints the lines
+// around them
+func showContext(config *Config, query Query, entries []LogEntry) error {
+	client := newLokiClient(config.LokiAddr, os.Getenv)
+	for {
+		fmt.Print("\nShow the lines around a result? (y/N): ")
+		answer, err := inputText("")
+		if err != nil {
+			return err
+		}
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer != "y" && answer != "yes" {
+			return nil
+		}
+
+		item, err := selectItem(config, contextItems(entries), "Select line:")
+		if err != nil {
+			return fmt.Errorf("line selection failed: %w", err)
+		}
+		number, _, _ := strings.Cut(item, " ")
+		index, err := strconv.Atoi(number)
+		if err != nil || index < 1 || index > len(entries) {
+			return fmt.Errorf("invalid line: %s", item)
+		}
+		entry := entries[index-1]
+
+		// Only the streams of the query can hold the line
+		selector := Query{Selectors: query.Selectors}.String()
+		series, err := lookup("Fetching streams...", func(ctx context.Context) ([]map[string]string, error) {
+			ctx, cancel := context.WithTimeout(ctx, lookupTimeout(config))
+			defer cancel()
+			return client.Series(ctx, selector, entry.Timestamp.Add(-streamLookupWindow), entry.Timestamp.Add(streamLookupWindow))
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get streams: %w", err)
+		}
+		stream, ok := entryStream(series, entry.Labels)
+		if !ok {
+			return fmt.Errorf("no stream of %s matches the line", selector)
+		}
+
+		before, after, err := fetchContext(config.LogCLICmd, entry, stream, config.ContextLines)
+		if err != nil {
+			return fmt.Errorf("failed to fetch context: %w", err)
+		}
+
+		// Highlight the picked line among its neighbours
+		renderer, err := newRenderer(os.Stdout, config.Format, config.UTC, []LineFilter{{Operator: "|=", Text: entry.Line}})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "\n%d lines before and %d after, from %s\n", len(before), len(after), Query{Selectors: seriesSelector(stream)})
+		for _, e := range slices.Concat(before, []LogEntry{entry}, after) {
+			if err := renderer.Render(e); err != nil {
+				return err
+			}
+		}
+		if err := renderer.Flush(); err != nil {
+			return err
+		}
+	}
+}
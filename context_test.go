@@ -0,0 +1,136 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEntryStream(t *testing.T) {
+	series := []map[string]string{
+		{"app": "nginx"},
+		{"app": "nginx", "env": "prod"},
+		{"app": "nginx", "pod": "nginx-1"},
+		{"app": "api", "status": "500"},
+	}
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   map[string]string
+	}{
+		{
+			name:   "parsed label named like a label of another stream",
+			labels: map[string]string{"app": "nginx", "status": "500", "trace_id": "abc"},
+			want:   map[string]string{"app": "nginx"},
+		},
+		{
+			name:   "most specific stream",
+			labels: map[string]string{"app": "nginx", "env": "prod", "level": "error"},
+			want:   map[string]string{"app": "nginx", "env": "prod"},
+		},
+		{
+			name:   "no stream",
+			labels: map[string]string{"app": "web"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := entryStream(series, tt.labels)
+			if ok != (tt.want != nil) || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("entryStream() = %v, %v, want %v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestContextArgs(t *testing.T) {
+	stream := []LabelSelector{{Label: "app", Operator: "=", Value: "nginx"}}
+	ts := time.Date(2025, 8, 14, 1, 0, 0, 500, time.UTC)
+
+	before := strings.Join(contextArgs("logcli", stream, ts, 5, false), " ")
+	want := `logcli query {app="nginx"} --from 2025-08-14T00:00:00.0000005Z --to 2025-08-14T01:00:00.000000501Z --limit 5`
+	if before != want {
+		t.Errorf("contextArgs() before = %s, want %s", before, want)
+	}
+
+	after := strings.Join(contextArgs("logcli", stream, ts, 5, true), " ")
+	want = `logcli query {app="nginx"} --from 2025-08-14T01:00:00.0000005Z --to 2025-08-14T02:00:00.0000005Z --limit 5 --forward`
+	if after != want {
+		t.Errorf("contextArgs() after = %s, want %s", after, want)
+	}
+
+	// Values are quoted, logcli must get valid LogQL
+	stream = []LabelSelector{{Label: "filename", Operator: "=", Value: `C:\logs\"a".log`}}
+	args := contextArgs("logcli", stream, ts, 5, false)
+	if want := "{filename=`C:\\logs\\\"a\".log`}"; args[2] != want {
+		t.Errorf("contextArgs() selector = %s, want %s", args[2], want)
+	}
+}
+
+func TestFetchContext(t *testing.T) {
+	// Lines before come newest first, lines after oldest first. Both windows
+	// include the entry and another line with the same timestamp.
+	logcli, calls := fakeLogCLI(t, `case "$*" in
+*--forward*)
+  echo '{"timestamp":"2025-08-14T01:00:00Z","labels":{"app":"nginx"},"line":"upstream error"}'
+  echo '{"timestamp":"2025-08-14T01:00:00Z","labels":{"app":"nginx"},"line":"same time"}'
+  echo '{"timestamp":"2025-08-14T01:00:02Z","labels":{"app":"nginx"},"line":"after 1"}'
+  echo '{"timestamp":"2025-08-14T01:00:03Z","labels":{"app":"nginx"},"line":"after 2"}'
+  echo '{"timestamp":"2025-08-14T01:00:04Z","labels":{"app":"nginx"},"line":"after 3"}' ;;
+*)
+  echo '{"timestamp":"2025-08-14T01:00:00Z","labels":{"app":"nginx"},"line":"same time"}'
+  echo '{"timestamp":"2025-08-14T01:00:00Z","labels":{"app":"nginx"},"line":"upstream error"}'
+  echo '{"timestamp":"2025-08-14T00:59:59Z","labels":{"app":"nginx"},"line":"before 2"}'
+  echo '{"timestamp":"2025-08-14T00:59:58Z","labels":{"app":"nginx"},"line":"before 1"}' ;;
+esac`)
+
+	entry := LogEntry{
+		Timestamp: time.Date(2025, 8, 14, 1, 0, 0, 0, time.UTC),
+		Labels:    map[string]string{"app": "nginx", "status": "500"},
+		Line:      "upstream error",
+	}
+	before, after, err := fetchContext(logcli, entry, map[string]string{"app": "nginx"}, 2)
+	if err != nil {
+		t.Fatalf("fetchContext() error = %v", err)
+	}
+
+	lines := func(entries []LogEntry) []string {
+		got := []string{}
+		for _, e := range entries {
+			got = append(got, e.Line)
+		}
+		return got
+	}
+	if got, want := lines(before), []string{"before 2", "same time"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fetchContext() before = %v, want %v", got, want)
+	}
+	if got, want := lines(after), []string{"after 1", "after 2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("fetchContext() after = %v, want %v", got, want)
+	}
+
+	// Room for the entry, and after it for the line already shown before it
+	got := readCalls(t, calls)
+	if len(got) != 2 || !strings.HasPrefix(got[0], `query {app="nginx"} `) ||
+		!strings.Contains(got[0], "--limit 3") || !strings.Contains(got[1], "--limit 4 --forward") {
+		t.Errorf("logcli called with %v", got)
+	}
+
+	if _, _, err := fetchContext(logcli, entry, nil, 2); err == nil {
+		t.Error("expected error for an entry without stream labels")
+	}
+}
+
+func TestContextItems(t *testing.T) {
+	entries := []LogEntry{{
+		Timestamp: time.Date(2025, 8, 14, 1, 0, 0, 0, time.UTC),
+		Labels:    map[string]string{"app": "nginx"},
+		Line:      "panic\ngoroutine 1",
+	}}
+	want := []string{`1 2025-08-14T01:00:00Z {app="nginx"} panic goroutine 1`}
+	if got := contextItems(entries); !reflect.DeepEqual(got, want) {
+		t.Errorf("contextItems() = %v, want %v", got, want)
+	}
+}
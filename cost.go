@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default cost limits, used when the configuration file does not set them
+const (
+	defaultMaxQueryBytes   = 10 << 30
+	defaultMaxQueryStreams = 10000
+)
+
+// CostLimits are the thresholds above which a query must be confirmed
+// A negative limit disables the check
+type CostLimits struct {
+	MaxBytes   int64
+	MaxStreams int64
+}
+
+// byteUnits are the suffixes accepted by parseByteSize, largest first
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// parseByteSize parses sizes like "512MB" or "10GB" (powers of 1024)
+func parseByteSize(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	for _, unit := range byteUnits {
+		if number, ok := strings.CutSuffix(value, unit.suffix); ok {
+			n, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid size: %s", s)
+			}
+			return int64(n * float64(unit.size)), nil
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size: %s (e.g. 512MB, 10GB)", s)
+	}
+	return n, nil
+}
+
+// formatBytes formats n like "1.5 GB"
+func formatBytes(n int64) string {
+	for _, unit := range byteUnits {
+		if n >= unit.size && unit.size > 1 {
+			return strconv.FormatFloat(float64(n)/float64(unit.size), 'f', 1, 64) + " " + unit.suffix
+		}
+	}
+	return strconv.FormatInt(n, 10) + " B"
+}
+
+// exceededLimits describes every limit the estimate is above
+func exceededLimits(stats IndexStats, limits CostLimits) []string {
+	exceeded := []string{}
+	if limits.MaxBytes >= 0 && stats.Bytes > limits.MaxBytes {
+		exceeded = append(exceeded, fmt.Sprintf("%s scanned exceeds the limit of %s", formatBytes(stats.Bytes), formatBytes(limits.MaxBytes)))
+	}
+	if limits.MaxStreams >= 0 && stats.Streams > limits.MaxStreams {
+		exceeded = append(exceeded, fmt.Sprintf("%d streams exceed the limit of %d", stats.Streams, limits.MaxStreams))
+	}
+	return exceeded
+}
+
+// confirmQueryCost estimates what the query scans in the time range and asks
+// for confirmation when that is above the configured limits. An estimate that
+// cannot be made only produces a warning.
+func confirmQueryCost(config *Config, query Query, timeArgs []string) error {
+	start, end, err := resolveTimeRange(timeArgs, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// Index stats only consider the stream selector, line filters are ignored
+	selector := Query{Selectors: query.Selectors}.String()
+	client := newLokiClient(config.LokiAddr, os.Getenv)
+	stats, err := lookup("Estimating query cost...", func(ctx context.Context) (IndexStats, error) {
+		ctx, cancel := context.WithTimeout(ctx, lookupTimeout(config))
+		defer cancel()
+		return client.IndexStats(ctx, selector, start, end)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not estimate query cost: %v\n", err)
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Estimated scan: %s in %d streams, %d entries\n", formatBytes(stats.Bytes), stats.Streams, stats.Entries)
+
+	exceeded := exceededLimits(stats, config.CostLimits)
+	if len(exceeded) == 0 {
+		return nil
+	}
+
+	fmt.Println("\nThis query is expensive:")
+	for _, reason := range exceeded {
+		fmt.Printf("- %s\n", reason)
+	}
+	fmt.Print("Run it anyway? (y/N): ")
+	answer, err := inputText("")
+	if err != nil {
+		return err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer != "y" && answer != "yes" {
+		return fmt.Errorf("query cancelled, narrow the selector or the time range")
+	}
+	return nil
+}
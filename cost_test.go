@@ -0,0 +1,98 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{input: "1024", want: 1024},
+		{input: "512MB", want: 512 << 20},
+		{input: "10 gb", want: 10 << 30},
+		{input: "1.5KB", want: 1536},
+		{input: "2TB", want: 2 << 40},
+		{input: "-1", want: -1},
+		{input: "lots", wantErr: true},
+		{input: "-1GB", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseByteSize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseByteSize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseByteSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:        "0 B",
+		512:      "512 B",
+		1536:     "1.5 KB",
+		10 << 30: "10.0 GB",
+		3 << 40:  "3.0 TB",
+	}
+	for n, want := range tests {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestExceededLimits(t *testing.T) {
+	limits := CostLimits{MaxBytes: 1 << 30, MaxStreams: 100}
+
+	if got := exceededLimits(IndexStats{Bytes: 1 << 20, Streams: 10}, limits); len(got) != 0 {
+		t.Errorf("exceededLimits() = %v, want none", got)
+	}
+
+	got := exceededLimits(IndexStats{Bytes: 2 << 30, Streams: 500}, limits)
+	want := []string{
+		"2.0 GB scanned exceeds the limit of 1.0 GB",
+		"500 streams exceed the limit of 100",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("exceededLimits() = %v, want %v", got, want)
+	}
+
+	disabled := CostLimits{MaxBytes: -1, MaxStreams: -1}
+	if got := exceededLimits(IndexStats{Bytes: 1 << 40, Streams: 1 << 20}, disabled); len(got) != 0 {
+		t.Errorf("exceededLimits() with disabled limits = %v", got)
+	}
+}
+
+func TestFileConfigCostLimits(t *testing.T) {
+	config := &FileConfig{}
+	limits, err := config.costLimits()
+	if err != nil {
+		t.Fatalf("costLimits() error = %v", err)
+	}
+	if limits.MaxBytes != defaultMaxQueryBytes || limits.MaxStreams != defaultMaxQueryStreams {
+		t.Errorf("costLimits() = %+v, want defaults", limits)
+	}
+
+	config.Cost.MaxBytes = "50GB"
+	config.Cost.MaxStreams = -1
+	limits, err = config.costLimits()
+	if err != nil {
+		t.Fatalf("costLimits() error = %v", err)
+	}
+	if limits.MaxBytes != 50<<30 || limits.MaxStreams != -1 {
+		t.Errorf("costLimits() = %+v", limits)
+	}
+
+	config.Cost.MaxBytes = "huge"
+	if _, err := config.costLimits(); err == nil {
+		t.Error("expected error for an invalid max_bytes")
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// diagnoseStage is the query up to one of its matchers or filters
+type diagnoseStage struct {
+	Step       string // Matcher or filter added in this stage
+	LineFilter bool   // Whether Step is a line filter
+	Metadata   bool   // Whether Step is a structured metadata filter
+	Query      Query
+}
+
+// diagnoseStages returns the query built up one matcher, then one metadata
+// filter, then one line filter, at a time. Positive matchers come first, since
+// LogQL needs one on its own.
+func diagnoseStages(query Query) []diagnoseStage {
+	selectors := make([]LabelSelector, 0, len(query.Selectors))
+	for _, s := range query.Selectors {
+		if s.Operator == "=" || s.Operator == "=~" {
+			selectors = append(selectors, s)
+		}
+	}
+	for _, s := range query.Selectors {
+		if s.Operator != "=" && s.Operator != "=~" {
+			selectors = append(selectors, s)
+		}
+	}
+
+	stages := []diagnoseStage{}
+	for i, s := range selectors {
+		step := s.String()
+		if i == 0 {
+			step = "{" + step + "}"
+		}
+		stages = append(stages, diagnoseStage{
+			Step:  step,
+			Query: Query{Selectors: selectors[:i+1]},
+		})
+	}
+	for i, m := range query.Metadata {
+		stages = append(stages, diagnoseStage{
+			Step:     "| " + m.String(),
+			Metadata: true,
+			Query:    Query{Selectors: selectors, Metadata: query.Metadata[:i+1]},
+		})
+	}
+	for i, f := range query.LineFilters {
+		stages = append(stages, diagnoseStage{
+			Step:       f.String(),
+			LineFilter: true,
+			Query:      Query{Selectors: selectors, Metadata: query.Metadata, LineFilters: query.LineFilters[:i+1]},
+		})
+	}
+	return stages
+}
+
+// countQuery builds the metric query counting the lines query returns within d
+func countQuery(query Query, d time.Duration) string {
+	return fmt.Sprintf("sum(count_over_time(%s [%s]))", query, apiDuration(d))
+}
+
+// countLines returns the number of lines query matches between start and end
+func countLines(ctx context.Context, logcliCmd string, query Query, start, end time.Time) (int64, error) {
+	cmd := exec.CommandContext(ctx, logcliCmd, "instant-query", "--quiet",
+		"--now", end.Format(time.RFC3339Nano), countQuery(query, end.Sub(start)))
+	isolateFromTerminal(cmd)
+
+	// stdout only holds the result, stderr is kept to classify failures
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, newLogCLIError("instant-query", err, stderr.String())
+	}
+
+	return parseInstantCount(output)
+}
+
+// parseInstantCount reads the single sample of a logcli instant-query vector
+// An empty vector means no lines matched
+func parseInstantCount(output []byte) (int64, error) {
+	var vector []struct {
+		Value []json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(output, &vector); err != nil {
+		return 0, fmt.Errorf("failed to parse instant-query output: %w", err)
+	}
+	if len(vector) == 0 {
+		return 0, nil
+	}
+	if len(vector[0].Value) != 2 {
+		return 0, fmt.Errorf("unexpected instant-query sample: %s", output)
+	}
+
+	var value string
+	if err := json.Unmarshal(vector[0].Value[1], &value); err != nil {
+		return 0, fmt.Errorf("unexpected instant-query sample: %s", output)
+	}
+	count, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected instant-query sample: %s", output)
+	}
+	return int64(count), nil
+}
+
+// diagnoseQuery reports how many lines each stage of the query leaves,
+// pointing to the stage that removed all results
+func diagnoseQuery(config *Config, query Query, timeArgs []string) error {
+	start, end, err := resolveTimeRange(timeArgs, time.Now())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nDiagnosing %s\nfrom %s to %s\n\n", query, start.Format(time.RFC3339), end.Format(time.RFC3339))
+
+	stages := diagnoseStages(query)
+	if len(stages) == 0 {
+		return fmt.Errorf("nothing to diagnose, the query has no label matchers")
+	}
+	width := 0
+	for _, stage := range stages {
+		width = max(width, len(stage.Step))
+	}
+
+	var last int64 = -1
+	emptied := -1
+	for i, stage := range stages {
+		count, err := lookup(fmt.Sprintf("Counting %s...", stage.Step), func(ctx context.Context) (int64, error) {
+			return countLines(ctx, config.LogCLICmd, stage.Query, start, end)
+		})
+		if err != nil {
+			return fmt.Errorf("stage %d failed: %w", i+1, err)
+		}
+
+		prefix := "  "
+		if i > 0 {
+			prefix = "+ "
+		}
+		note := ""
+		if count == 0 && last != 0 {
+			note = "  <- no lines left"
+			emptied = i
+		}
+		fmt.Printf("%s%-*s %10d lines%s\n", prefix, width, stage.Step, count, note)
+		last = count
+	}
+
+	fmt.Println()
+	switch {
+	case emptied < 0:
+		fmt.Printf("Every stage matches logs, the query returns %d lines.\n", last)
+	case emptied == 0:
+		fmt.Printf("No logs match %s in this time range. Check the label value or widen the time range.\n", stages[0].Step)
+	case stages[emptied].LineFilter:
+		fmt.Printf("The line filter %s removes all remaining lines.\n", stages[emptied].Step)
+	case stages[emptied].Metadata:
+		fmt.Printf("The structured metadata filter %s removes all remaining lines.\n", stages[emptied].Step)
+	default:
+		fmt.Printf("The matcher %s removes all remaining streams.\n", stages[emptied].Step)
+	}
+	return nil
+}
+
+// promptForDiagnose asks whether to diagnose a query that returned nothing
+func promptForDiagnose() (bool, error) {
+	fmt.Print("\nThe query returned no lines. Diagnose it? (y/N): ")
+	answer, err := inputText("")
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
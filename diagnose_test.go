@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiagnoseStages(t *testing.T) {
+	query := Query{
+		Selectors: []LabelSelector{
+			{Label: "namespace", Operator: "!=", Value: "prod-canary"},
+			{Label: "namespace", Operator: "=~", Value: "prod-.*"},
+			{Label: "app", Operator: "=", Value: "nginx"},
+		},
+		Metadata: []LabelSelector{
+			{Label: "trace_id", Operator: "=", Value: "abc"},
+		},
+		LineFilters: []LineFilter{
+			{Operator: "|=", Text: "error"},
+			{Operator: "!=", Text: "healthz"},
+		},
+	}
+
+	want := []struct {
+		step  string
+		query string
+	}{
+		{`{namespace=~"prod-.*"}`, `{namespace=~"prod-.*"}`},
+		{`app="nginx"`, `{namespace=~"prod-.*",app="nginx"}`},
+		{`namespace!="prod-canary"`, `{namespace=~"prod-.*",app="nginx",namespace!="prod-canary"}`},
+		{`| trace_id="abc"`, `{namespace=~"prod-.*",app="nginx",namespace!="prod-canary"} | trace_id="abc"`},
+		{`|= "error"`, `{namespace=~"prod-.*",app="nginx",namespace!="prod-canary"} | trace_id="abc" |= "error"`},
+		{`!= "healthz"`, `{namespace=~"prod-.*",app="nginx",namespace!="prod-canary"} | trace_id="abc" |= "error" != "healthz"`},
+	}
+
+	stages := diagnoseStages(query)
+	if len(stages) != len(want) {
+		t.Fatalf("diagnoseStages() returned %d stages, want %d", len(stages), len(want))
+	}
+	for i, w := range want {
+		if stages[i].Step != w.step || stages[i].Query.String() != w.query {
+			t.Errorf("stage %d = %q %q, want %q %q", i+1, stages[i].Step, stages[i].Query, w.step, w.query)
+		}
+		if stages[i].Metadata != (i == 3) {
+			t.Errorf("stage %d Metadata = %v", i+1, stages[i].Metadata)
+		}
+		if stages[i].LineFilter != (i >= 4) {
+			t.Errorf("stage %d LineFilter = %v", i+1, stages[i].LineFilter)
+		}
+	}
+}
+
+func TestParseInstantCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    int64
+		wantErr bool
+	}{
+		{name: "empty vector", output: "[]", want: 0},
+		{name: "sample", output: `[{"metric":{},"value":[1755133200,"1234"]}]`, want: 1234},
+		{name: "exponent", output: `[{"metric":{},"value":[1755133200,"1.5e+06"]}]`, want: 1500000},
+		{name: "not json", output: "error", wantErr: true},
+		{name: "bad sample", output: `[{"metric":{},"value":[1755133200]}]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseInstantCount([]byte(tt.output))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseInstantCount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseInstantCount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	logcli, calls := fakeLogCLI(t, `echo '[{"metric":{},"value":[1755133200,"42"]}]'`)
+	end := time.Date(2025, 8, 14, 18, 0, 0, 0, time.UTC)
+	query := Query{Selectors: []LabelSelector{{Label: "app", Operator: "=", Value: "nginx"}}}
+
+	got, err := countLines(context.Background(), logcli, query, end.Add(-6*time.Hour), end)
+	if err != nil {
+		t.Fatalf("countLines() error = %v", err)
+	}
+	if got != 42 {
+		t.Errorf("countLines() = %d, want 42", got)
+	}
+
+	want := `instant-query --quiet --now 2025-08-14T18:00:00Z sum(count_over_time({app="nginx"} [6h]))`
+	if c := readCalls(t, calls); len(c) != 1 || !strings.Contains(c[0], want) {
+		t.Errorf("logcli called with %v, want %s", c, want)
+	}
+}
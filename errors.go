@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"strings"
+)
+
+// Known causes of logcli failures, matched with errors.Is
+var (
+	ErrLogCLINotInstalled = errors.New("logcli is not installed")
+	ErrConnectionRefused  = errors.New("cannot connect to Loki")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrForbidden          = errors.New("forbidden")
+	ErrTenantMissing      = errors.New("tenant (org ID) missing")
+	ErrQueryTooLong       = errors.New("query time range too long")
+	ErrRateLimited        = errors.New("rate limited")
+	ErrInvalidQuery       = errors.New("invalid query")
+)
+
+// errorPatterns maps logcli output to a known cause, checked in order
+// "no org id" comes first since Loki reports it with a 401 status
+var errorPatterns = []struct {
+	kind     error
+	patterns []string
+}{
+	{ErrTenantMissing, []string{"no org id", "x-scope-orgid"}},
+	{ErrUnauthorized, []string{"unauthorized"}},
+	{ErrForbidden, []string{"forbidden"}},
+	{ErrQueryTooLong, []string{"query time range exceeds the limit", "max_query_length", "query too long"}},
+	{ErrRateLimited, []string{"too many requests", "rate limit", "too many outstanding requests"}},
+	{ErrInvalidQuery, []string{"parse error", "syntax error"}},
+	{ErrConnectionRefused, []string{"connection refused", "no such host", "network is unreachable", "no route to host"}},
+}
+
+// errorHints are actionable suggestions shown for each known cause
+var errorHints = map[error]string{
+	ErrLogCLINotInstalled: "Install logcli and make sure it is in PATH: https://grafana.com/docs/loki/latest/query/logcli/",
+	ErrConnectionRefused:  "Check that LOKI_ADDR points to a running, reachable Loki instance.",
+	ErrUnauthorized:       "Set LOKI_USERNAME and LOKI_PASSWORD, or LOKI_BEARER_TOKEN, to valid credentials.",
+	ErrForbidden:          "Your credentials are valid but not allowed to access this tenant or endpoint.",
+	ErrTenantMissing:      "Set LOKI_ORG_ID to the tenant you want to query.",
+	ErrQueryTooLong:       "Choose a shorter time range, or split it with -export -chunk.",
+	ErrRateLimited:        "Loki is rate limiting requests. Wait a moment, then retry or narrow the query.",
+	ErrInvalidQuery:       "Loki cannot parse the query. Check the quoting of values and the pipeline stages.",
+}
+
+// LogCLIError is a failed logcli invocation
+type LogCLIError struct {
+	Command string // logcli subcommand, e.g. "labels app"
+	Kind    error  // One of the Err* causes, nil if unknown
+	Output  string // Captured logcli output
+	Err     error  // Underlying error from running logcli
+}
+
+// newLogCLIError wraps a failed logcli invocation and classifies its cause
+func newLogCLIError(command string, err error, output string) *LogCLIError {
+	return &LogCLIError{
+		Command: command,
+		Kind:    classifyLogCLIError(err, output),
+		Output:  strings.TrimSpace(output),
+		Err:     err,
+	}
+}
+
+func (e *LogCLIError) Error() string {
+	msg := fmt.Sprintf("logcli %s failed: %v", e.Command, e.Err)
+	if e.Kind != nil {
+		msg = fmt.Sprintf("logcli %s failed: %v (%v)", e.Command, e.Kind, e.Err)
+	}
+	if e.Output != "" {
+		msg += "\nOutput: " + e.Output
+	}
+	return msg
+}
+
+// Unwrap exposes both the classified cause and the underlying error
+func (e *LogCLIError) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// Hint returns an actionable suggestion for the failure, or ""
+func (e *LogCLIError) Hint() string {
+	return errorHints[e.Kind]
+}
+
+// classifyLogCLIError returns the known cause of a logcli failure, or nil
+func classifyLogCLIError(err error, output string) error {
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+		return ErrLogCLINotInstalled
+	}
+
+	text := strings.ToLower(output)
+	for _, p := range errorPatterns {
+		for _, pattern := range p.patterns {
+			if strings.Contains(text, pattern) {
+				return p.kind
+			}
+		}
+	}
+	return nil
+}
+
+// LokiAPIError is a failed request to the Loki HTTP API
+type LokiAPIError struct {
+	Path   string // API endpoint, e.g. "/loki/api/v1/series"
+	Status string // HTTP status, empty if no response arrived
+	Kind   error  // One of the Err* causes, nil if unknown
+	Body   string // Response body
+	Err    error  // Transport error, nil if Loki responded
+}
+
+// newLokiAPIError wraps a failed API request and classifies its cause like
+// logcli output, Loki reports the same problems through both
+func newLokiAPIError(path, status, body string, err error) *LokiAPIError {
+	output := status + " " + body
+	if err != nil {
+		output = err.Error()
+	}
+	return &LokiAPIError{
+		Path:   path,
+		Status: status,
+		Kind:   classifyLogCLIError(err, output),
+		Body:   strings.TrimSpace(body),
+		Err:    err,
+	}
+}
+
+func (e *LokiAPIError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Path, e.Err)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Path, e.Status, e.Body)
+}
+
+// Unwrap exposes both the classified cause and the transport error
+func (e *LokiAPIError) Unwrap() []error {
+	errs := []error{}
+	for _, err := range []error{e.Kind, e.Err} {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Hint returns an actionable suggestion for the failure, or ""
+func (e *LokiAPIError) Hint() string {
+	return errorHints[e.Kind]
+}
+
+// errorHint returns the hint for the first LogCLIError or LokiAPIError in
+// err's chain, or ""
+func errorHint(err error) string {
+	var hinted interface{ Hint() string }
+	if errors.As(err, &hinted) {
+		return hinted.Hint()
+	}
+	return ""
+}
+
+// isTransient reports whether a failed discovery call is worth retrying
+func isTransient(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrConnectionRefused) ||
+		errors.Is(err, ErrRateLimited) {
+		return true
+	}
+
+	msg := err.Error()
+	if kind := classifyLogCLIError(err, msg); kind == ErrConnectionRefused || kind == ErrRateLimited {
+		return true
+	}
+	for _, s := range []string{"connection reset", "i/o timeout", "EOF", "502", "503", "504"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestClassifyLogCLIError(t *testing.T) {
+	exitErr := errors.New("exit status 1")
+
+	tests := []struct {
+		name   string
+		err    error
+		output string
+		want   error
+	}{
+		{
+			name: "logcli not installed",
+			err:  &exec.Error{Name: "logcli", Err: exec.ErrNotFound},
+			want: ErrLogCLINotInstalled,
+		},
+		{
+			name:   "connection refused",
+			err:    exitErr,
+			output: `Query failed: Get "http://localhost:3100/loki/api/v1/labels": dial tcp [::1]:3100: connect: connection refused`,
+			want:   ErrConnectionRefused,
+		},
+		{
+			name:   "unauthorized",
+			err:    exitErr,
+			output: "Error response from server: 401 Unauthorized (<nil>) attempts remaining: 0",
+			want:   ErrUnauthorized,
+		},
+		{
+			name:   "forbidden",
+			err:    exitErr,
+			output: "Error response from server: 403 Forbidden (<nil>)",
+			want:   ErrForbidden,
+		},
+		{
+			name:   "tenant missing is reported with 401",
+			err:    exitErr,
+			output: "Error response from server: no org id (<nil>) attempts remaining: 0",
+			want:   ErrTenantMissing,
+		},
+		{
+			name:   "query too long",
+			err:    exitErr,
+			output: "Error response from server: the query time range exceeds the limit (query length: 745h0m0s, limit: 721h0m0s)",
+			want:   ErrQueryTooLong,
+		},
+		{
+			name:   "rate limited",
+			err:    exitErr,
+			output: "Error response from server: 429 Too Many Requests",
+			want:   ErrRateLimited,
+		},
+		{
+			name:   "invalid query",
+			err:    exitErr,
+			output: "Error response from server: parse error at line 1, col 5: syntax error: unexpected IDENTIFIER (400 Bad Request)",
+			want:   ErrInvalidQuery,
+		},
+		{
+			name:   "unknown",
+			err:    exitErr,
+			output: "panic: runtime error: index out of range",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newLogCLIError("labels", tt.err, tt.output)
+			if err.Kind != tt.want {
+				t.Errorf("Kind = %v, want %v", err.Kind, tt.want)
+			}
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) {
+					t.Errorf("errors.Is(err, %v) = false", tt.want)
+				}
+				if err.Hint() == "" {
+					t.Error("Hint() is empty for a known cause")
+				}
+			}
+			if !errors.Is(err, tt.err) {
+				t.Error("underlying error is not unwrapped")
+			}
+		})
+	}
+}
+
+func TestLogCLIErrorMessage(t *testing.T) {
+	err := newLogCLIError("labels app", errors.New("exit status 1"), "Error response from server: 401 Unauthorized\n")
+
+	want := "logcli labels app failed: unauthorized (exit status 1)\nOutput: Error response from server: 401 Unauthorized"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+
+	// Hints are found through wrapping
+	wrapped := fmt.Errorf("label selection failed: %w", err)
+	if hint := errorHint(wrapped); !strings.Contains(hint, "LOKI_USERNAME") {
+		t.Errorf("errorHint() = %q", hint)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: fmt.Errorf("logcli labels timed out: %w", context.DeadlineExceeded), want: true},
+		{err: errors.New("dial tcp 127.0.0.1:3100: connect: connection refused"), want: true},
+		{err: errors.New("Error response from server: 503 Service Unavailable"), want: true},
+		{err: errors.New("Error response from server: 401 Unauthorized"), want: false},
+		{err: errors.New("parse error at line 1"), want: false},
+	}
+
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("isTransient(%q) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// explorePane is a single pane of the Grafana Explore "panes" URL parameter
+type explorePane struct {
+	Datasource string         `json:"datasource,omitempty"`
+	Queries    []exploreQuery `json:"queries"`
+	Range      exploreRange   `json:"range"`
+}
+
+type exploreQuery struct {
+	RefID      string             `json:"refId"`
+	Expr       string             `json:"expr"`
+	QueryType  string             `json:"queryType"`
+	Datasource *exploreDatasource `json:"datasource,omitempty"`
+}
+
+type exploreDatasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type exploreRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// exploreTimeRange converts time range arguments into Grafana's range format:
+// relative ranges as now-<duration>, absolute ranges as epoch milliseconds
+func exploreTimeRange(timeArgs []string) (exploreRange, error) {
+	if len(timeArgs) == 2 && timeArgs[0] == "--since" {
+		d, err := parseSince(timeArgs[1])
+		if err != nil {
+			return exploreRange{}, err
+		}
+		return exploreRange{From: "now-" + grafanaDuration(d), To: "now"}, nil
+	}
+
+	start, end, err := resolveTimeRange(timeArgs, time.Now())
+	if err != nil {
+		return exploreRange{}, err
+	}
+	return exploreRange{
+		From: strconv.FormatInt(start.UnixMilli(), 10),
+		To:   strconv.FormatInt(end.UnixMilli(), 10),
+	}, nil
+}
+
+// grafanaDuration formats d in the largest unit Grafana understands
+// that represents it exactly
+func grafanaDuration(d time.Duration) string {
+	units := []struct {
+		suffix string
+		size   time.Duration
+	}{
+		{"w", 7 * 24 * time.Hour},
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+	}
+	for _, u := range units {
+		if d%u.size == 0 {
+			return strconv.FormatInt(int64(d/u.size), 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(int64(d/time.Second), 10) + "s"
+}
+
+// validateExplore checks that a Grafana URL is set when an Explore link is
+// needed, so a missing one is reported before the interactive session
+func validateExplore(output string, showExplore, openBrowser bool, grafanaURL string) error {
+	if grafanaURL != "" || (output != OutputExplore && !showExplore && !openBrowser) {
+		return nil
+	}
+	return fmt.Errorf("grafana URL is not set (use -grafana-url or GRAFANA_URL)")
+}
+
+// buildExploreURL returns a Grafana Explore link showing query over the time range
+// The datasource UID may be empty to use Grafana's default datasource
+func buildExploreURL(grafanaURL string, datasourceUID string, query string, timeArgs []string) (string, error) {
+	if grafanaURL == "" {
+		return "", fmt.Errorf("grafana URL is not set (use -grafana-url or GRAFANA_URL)")
+	}
+
+	timeRange, err := exploreTimeRange(timeArgs)
+	if err != nil {
+		return "", err
+	}
+
+	q := exploreQuery{RefID: "A", Expr: query, QueryType: "range"}
+	if datasourceUID != "" {
+		q.Datasource = &exploreDatasource{Type: "loki", UID: datasourceUID}
+	}
+
+	panes := map[string]explorePane{
+		"loqui": {
+			Datasource: datasourceUID,
+			Queries:    []exploreQuery{q},
+			Range:      timeRange,
+		},
+	}
+	data, err := json.Marshal(panes)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("schemaVersion", "1")
+	params.Set("panes", string(data))
+
+	return strings.TrimRight(grafanaURL, "/") + "/explore?" + params.Encode(), nil
+}
+
+// openBrowser opens link in the user's default browser without waiting for it
+func openBrowser(link string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", link)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", link)
+	default:
+		cmd = exec.Command("xdg-open", link)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open browser: %w", err)
+	}
+	return nil
+}
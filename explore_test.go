@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGrafanaDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 14 * 24 * time.Hour, want: "2w"},
+		{d: 3 * 24 * time.Hour, want: "3d"},
+		{d: 36 * time.Hour, want: "36h"},
+		{d: 90 * time.Minute, want: "90m"},
+		{d: 45 * time.Second, want: "45s"},
+	}
+
+	for _, tt := range tests {
+		if got := grafanaDuration(tt.d); got != tt.want {
+			t.Errorf("grafanaDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestBuildExploreURL(t *testing.T) {
+	query := `{app="nginx"} |= "error"`
+
+	tests := []struct {
+		name          string
+		datasourceUID string
+		timeArgs      []string
+		wantRange     exploreRange
+	}{
+		{
+			name:          "relative range",
+			datasourceUID: "loki-uid",
+			timeArgs:      []string{"--since", "24h"},
+			wantRange:     exploreRange{From: "now-1d", To: "now"},
+		},
+		{
+			name:      "absolute range without datasource",
+			timeArgs:  []string{"--from", "2025-08-14T09:00:00+09:00", "--to", "2025-08-14T18:00:00+09:00"},
+			wantRange: exploreRange{From: "1755129600000", To: "1755162000000"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildExploreURL("https://grafana.example.com/", tt.datasourceUID, query, tt.timeArgs)
+			if err != nil {
+				t.Fatalf("buildExploreURL() error = %v", err)
+			}
+
+			prefix := "https://grafana.example.com/explore?"
+			if !strings.HasPrefix(got, prefix) {
+				t.Fatalf("buildExploreURL() = %q, want prefix %q", got, prefix)
+			}
+
+			params, err := url.ParseQuery(strings.TrimPrefix(got, prefix))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if params.Get("schemaVersion") != "1" {
+				t.Errorf("schemaVersion = %q", params.Get("schemaVersion"))
+			}
+
+			var panes map[string]explorePane
+			if err := json.Unmarshal([]byte(params.Get("panes")), &panes); err != nil {
+				t.Fatalf("invalid panes: %v", err)
+			}
+			pane := panes["loqui"]
+			if pane.Range != tt.wantRange {
+				t.Errorf("range = %+v, want %+v", pane.Range, tt.wantRange)
+			}
+			if len(pane.Queries) != 1 || pane.Queries[0].Expr != query {
+				t.Errorf("queries = %+v", pane.Queries)
+			}
+			if pane.Datasource != tt.datasourceUID {
+				t.Errorf("datasource = %q, want %q", pane.Datasource, tt.datasourceUID)
+			}
+			if (pane.Queries[0].Datasource != nil) != (tt.datasourceUID != "") {
+				t.Errorf("query datasource = %+v", pane.Queries[0].Datasource)
+			}
+		})
+	}
+}
+
+func TestBuildExploreURLWithoutGrafanaURL(t *testing.T) {
+	if _, err := buildExploreURL("", "", `{app="nginx"}`, []string{"--since", "1h"}); err == nil {
+		t.Error("expected error when the Grafana URL is missing")
+	}
+}
+
+func TestValidateExplore(t *testing.T) {
+	tests := []struct {
+		name        string
+		output      string
+		showExplore bool
+		openBrowser bool
+		grafanaURL  string
+		wantErr     bool
+	}{
+		{name: "command", output: OutputLogCLI},
+		{name: "explore output", output: OutputExplore, wantErr: true},
+		{name: "explore flag", output: OutputLogCLI, showExplore: true, wantErr: true},
+		{name: "open flag", output: OutputLogCLI, openBrowser: true, wantErr: true},
+		{name: "with grafana URL", output: OutputExplore, openBrowser: true, grafanaURL: "https://grafana.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateExplore(tt.output, tt.showExplore, tt.openBrowser, tt.grafanaURL)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateExplore() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExportOptions controls how query results are written to disk
+type ExportOptions struct {
+	Path        string        // Output file, gzip compressed when it ends in .gz
+	Format      string        // Renderer format used for each line
+	UTC         bool          // Write timestamps in UTC instead of local time
+	ChunkSize   time.Duration // Time range fetched by a single logcli call
+	Parallel    int           // Number of chunks fetched concurrently
+	SplitTime   bool          // Write one file per chunk
+	SplitLabels []string      // Write one file per combination of these label values
+	BatchSize   int           // logcli --batch for each chunk
+}
+
+// exportChunk is one slice of the selected time range
+type exportChunk struct {
+	Start time.Time
+	End   time.Time
+	Part  string // Temporary jsonl file holding the fetched entries
+	Lines int
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// missingLabelName stands for a missing label in split file names. Values
+// are cleaned with unsafeFileChars, so none can turn into it.
+const missingLabelName = "%none"
+
+// maxOpenExportFiles bounds the files kept open while splitting by labels;
+// the least recently written one is closed and reopened when needed
+var maxOpenExportFiles = 64
+
+// splitTimeRange divides [start, end) into consecutive chunks of at most size
+func splitTimeRange(start, end time.Time, size time.Duration) []exportChunk {
+	chunks := []exportChunk{}
+	for s := start; s.Before(end); s = s.Add(size) {
+		e := s.Add(size)
+		if e.After(end) {
+			e = end
+		}
+		chunks = append(chunks, exportChunk{Start: s, End: e})
+	}
+	return chunks
+}
+
+// exportFileName returns the file an entry is written to when splitting output
+func exportFileName(path string, chunk exportChunk, splitTime bool, splitLabels []string, labels map[string]string) string {
+	suffixes := []string{}
+	if splitTime {
+		suffixes = append(suffixes, chunk.Start.UTC().Format("20060102T150405Z"))
+	}
+	for _, label := range splitLabels {
+		value := labels[label]
+		if value == "" {
+			suffixes = append(suffixes, missingLabelName)
+			continue
+		}
+		suffixes = append(suffixes, unsafeFileChars.ReplaceAllString(value, "_"))
+	}
+	if len(suffixes) == 0 {
+		return path
+	}
+
+	// Keep the extensions (e.g. .jsonl.gz) at the end of the file name
+	dir, base := filepath.Split(path)
+	name, ext, _ := strings.Cut(base, ".")
+	if ext != "" {
+		ext = "." + ext
+	}
+	return filepath.Join(dir, name+"-"+strings.Join(suffixes, "-")+ext)
+}
+
+// exportQuery fetches the query results for the time range in parallel chunks
+// and writes them to one or more files
+func exportQuery(logcliCmd string, query Query, timeArgs []string, opts ExportOptions) error {
+	if opts.Format == FormatColor {
+		return fmt.Errorf("format %s cannot be exported", opts.Format)
+	}
+
+	start, end, err := resolveTimeRange(timeArgs, time.Now())
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "loqui-export-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	chunks := splitTimeRange(start, end, opts.ChunkSize)
+	for i := range chunks {
+		chunks[i].Part = filepath.Join(tmpDir, strconv.Itoa(i)+".jsonl")
+	}
+
+	if err := fetchChunks(logcliCmd, query, chunks, opts); err != nil {
+		return err
+	}
+
+	return writeChunks(chunks, opts)
+}
+
+// fetchChunks runs logcli for every chunk with bounded parallelism,
+// reporting progress on stderr
+func fetchChunks(logcliCmd string, query Query, chunks []exportChunk, opts ExportOptions) error {
+	parallel := opts.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	var (
+		mu       sync.Mutex
+		done     int
+		total    int
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	sem := make(chan struct{}, parallel)
+
+	progress := func() {
+		fmt.Fprintf(os.Stderr, "\rFetching chunks: %d/%d (%d lines)", done, len(chunks), total)
+	}
+	progress()
+
+	for i := range chunks {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(chunk *exportChunk) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			err := fetchChunk(logcliCmd, query, chunk, opts.BatchSize)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("chunk %s - %s: %w", chunk.Start.Format(time.RFC3339), chunk.End.Format(time.RFC3339), err)
+			}
+			done++
+			total += chunk.Lines
+			progress()
+		}(&chunks[i])
+	}
+
+	wg.Wait()
+	fmt.Fprintln(os.Stderr)
+
+	return firstErr
+}
+
+// fetchChunk stores all entries of one chunk in its temporary part file
+func fetchChunk(logcliCmd string, query Query, chunk *exportChunk, batchSize int) error {
+	f, err := os.Create(chunk.Part)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+
+	chunkArgs := []string{
+		"--from", chunk.Start.Format(time.RFC3339Nano),
+		"--to", chunk.End.Format(time.RFC3339Nano),
+	}
+	chunkArgs = append(chunkArgs, resultArgs(0, DirectionForward, batchSize)...)
+	args := buildLogCLIArgs(logcliCmd, query, chunkArgs)
+
+	err = streamLogCLIQuery(args, func(e LogEntry) error {
+		chunk.Lines++
+		return enc.Encode(e)
+	})
+	if err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+// exportFile is an open output file with optional gzip compression
+type exportFile struct {
+	f        *os.File
+	gz       *gzip.Writer
+	buf      *bufio.Writer
+	renderer *Renderer
+	lastUse  int // Order of the last write, to close the least recent file
+}
+
+// createExportFile creates path, or appends to it when it was written before
+// and closed to save file handles. Gzip supports several appended streams.
+func createExportFile(path string, format string, utc bool, appending bool) (*exportFile, error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appending {
+		flags = os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0o666)
+	if err != nil {
+		return nil, err
+	}
+
+	ef := &exportFile{f: f}
+	var w io.Writer = f
+	if strings.HasSuffix(path, ".gz") {
+		ef.gz = gzip.NewWriter(f)
+		w = ef.gz
+	}
+	ef.buf = bufio.NewWriter(w)
+
+	if appending {
+		ef.renderer, err = newAppendRenderer(ef.buf, format, utc)
+	} else {
+		ef.renderer, err = newRenderer(ef.buf, format, utc, nil)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return ef, nil
+}
+
+func (ef *exportFile) Close() error {
+	err := ef.renderer.Flush()
+	if flushErr := ef.buf.Flush(); err == nil {
+		err = flushErr
+	}
+	if ef.gz != nil {
+		if gzErr := ef.gz.Close(); err == nil {
+			err = gzErr
+		}
+	}
+	if closeErr := ef.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeChunks copies the fetched entries in chronological chunk order
+// to their output files
+func writeChunks(chunks []exportChunk, opts ExportOptions) (err error) {
+	files := map[string]*exportFile{} // Open files
+	created := map[string]bool{}
+	defer func() {
+		for _, ef := range files {
+			if closeErr := ef.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}()
+
+	lines := 0
+	open := func(name string) (*exportFile, error) {
+		if len(files) >= maxOpenExportFiles {
+			oldest := ""
+			for n, ef := range files {
+				if oldest == "" || ef.lastUse < files[oldest].lastUse {
+					oldest = n
+				}
+			}
+			err := files[oldest].Close()
+			delete(files, oldest)
+			if err != nil {
+				return nil, err
+			}
+		}
+		ef, err := createExportFile(name, opts.Format, opts.UTC, created[name])
+		if err != nil {
+			return nil, err
+		}
+		files[name] = ef
+		created[name] = true
+		return ef, nil
+	}
+
+	for _, chunk := range chunks {
+		f, err := os.Open(chunk.Part)
+		if err != nil {
+			return err
+		}
+
+		err = scanLogEntries(f, func(e LogEntry) error {
+			name := exportFileName(opts.Path, chunk, opts.SplitTime, opts.SplitLabels, e.Labels)
+			ef, ok := files[name]
+			if !ok {
+				var err error
+				if ef, err = open(name); err != nil {
+					return err
+				}
+			}
+			lines++
+			ef.lastUse = lines
+			return ef.renderer.Render(e)
+		})
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(created) == 0 {
+		// Still create the requested file so an empty result is visible
+		if _, err := open(opts.Path); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d lines to %d file(s)\n", lines, len(created))
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitTimeRange(t *testing.T) {
+	start := time.Date(2025, 8, 14, 9, 0, 0, 0, time.UTC)
+	end := start.Add(150 * time.Minute)
+
+	chunks := splitTimeRange(start, end, time.Hour)
+	if len(chunks) != 3 {
+		t.Fatalf("splitTimeRange() returned %d chunks, want 3", len(chunks))
+	}
+	if !chunks[0].Start.Equal(start) || !chunks[0].End.Equal(start.Add(time.Hour)) {
+		t.Errorf("first chunk = %v - %v", chunks[0].Start, chunks[0].End)
+	}
+	if !chunks[2].Start.Equal(start.Add(2*time.Hour)) || !chunks[2].End.Equal(end) {
+		t.Errorf("last chunk = %v - %v", chunks[2].Start, chunks[2].End)
+	}
+}
+
+func TestExportFileName(t *testing.T) {
+	chunk := exportChunk{Start: time.Date(2025, 8, 14, 9, 0, 0, 0, time.UTC)}
+	labels := map[string]string{"app": "nginx", "namespace": "prod/web", "level": "none"}
+
+	tests := []struct {
+		name        string
+		path        string
+		splitTime   bool
+		splitLabels []string
+		want        string
+	}{
+		{name: "no split", path: "out/logs.jsonl.gz", want: "out/logs.jsonl.gz"},
+		{name: "split by time", path: "out/logs.jsonl.gz", splitTime: true, want: "out/logs-20250814T090000Z.jsonl.gz"},
+		{name: "split by labels", path: "logs.txt", splitLabels: []string{"app", "namespace", "env"}, want: "logs-nginx-prod_web-%none.txt"},
+		{name: "value none is not a missing label", path: "logs.txt", splitLabels: []string{"level"}, want: "logs-none.txt"},
+		{name: "split by both", path: "logs", splitTime: true, splitLabels: []string{"app"}, want: "logs-20250814T090000Z-nginx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := exportFileName(tt.path, chunk, tt.splitTime, tt.splitLabels, labels)
+			if got != tt.want {
+				t.Errorf("exportFileName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteChunks(t *testing.T) {
+	dir := t.TempDir()
+
+	parts := []string{
+		`{"labels":{"app":"api"},"line":"one","timestamp":"2025-08-14T09:00:00Z"}
+{"labels":{"app":"web"},"line":"two","timestamp":"2025-08-14T09:30:00Z"}
+`,
+		`{"labels":{"app":"api"},"line":"three","timestamp":"2025-08-14T10:00:00Z"}
+`,
+	}
+	chunks := []exportChunk{}
+	for i, content := range parts {
+		part := filepath.Join(dir, "part"+string(rune('0'+i)))
+		if err := os.WriteFile(part, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, exportChunk{Part: part})
+	}
+
+	opts := ExportOptions{
+		Path:        filepath.Join(dir, "out.log.gz"),
+		Format:      FormatRaw,
+		SplitLabels: []string{"app"},
+	}
+	if err := writeChunks(chunks, opts); err != nil {
+		t.Fatalf("writeChunks() error = %v", err)
+	}
+
+	want := map[string]string{
+		"out-api.log.gz": "one\nthree\n",
+		"out-web.log.gz": "two\n",
+	}
+	for name, content := range want {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("missing export file: %v", err)
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(gz)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Errorf("%s = %q, want %q", name, got, content)
+		}
+	}
+}
+
+func TestWriteChunksReopensFiles(t *testing.T) {
+	// With one open file at a time, every change of app closes and reopens
+	defer func(n int) { maxOpenExportFiles = n }(maxOpenExportFiles)
+	maxOpenExportFiles = 1
+
+	dir := t.TempDir()
+	part := filepath.Join(dir, "part")
+	content := `{"labels":{"app":"api"},"line":"one","timestamp":"2025-08-14T09:00:00Z"}
+{"labels":{"app":"web"},"line":"two","timestamp":"2025-08-14T09:30:00Z"}
+{"labels":{"app":"api"},"line":"three","timestamp":"2025-08-14T10:00:00Z"}
+`
+	if err := os.WriteFile(part, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"out.csv", "timestamp,labels,line\n2025-08-14T09:00:00Z,\"{app=\"\"api\"\"}\",one\n2025-08-14T10:00:00Z,\"{app=\"\"api\"\"}\",three\n"},
+		{"out.log.gz", "one\nthree\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			format := FormatRaw
+			if strings.HasSuffix(tt.path, ".csv") {
+				format = FormatCSV
+			}
+			opts := ExportOptions{Path: filepath.Join(dir, tt.path), Format: format, UTC: true, SplitLabels: []string{"app"}}
+			if err := writeChunks([]exportChunk{{Part: part}}, opts); err != nil {
+				t.Fatalf("writeChunks() error = %v", err)
+			}
+
+			name := exportFileName(opts.Path, exportChunk{}, false, opts.SplitLabels, map[string]string{"app": "api"})
+			f, err := os.Open(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			var r io.Reader = f
+			if strings.HasSuffix(name, ".gz") {
+				if r, err = gzip.NewReader(f); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("%s = %q, want %q", filepath.Base(name), got, tt.want)
+			}
+		})
+	}
+}
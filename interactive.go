@@ -2,9 +2,11 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -18,11 +20,25 @@ type LabelSelector struct {
 type LineFilter struct {
 	Operator string
 	Text     string
+	Or       []string // Further terms matched like Text, |= "a" or "b"
+	IP       bool     // Text is an address, range or CIDR for ip("...")
 }
 
+// Result directions for logcli query
+const (
+	DirectionBackward = "backward"
+	DirectionForward  = "forward"
+)
+
+// logcli defaults for --limit and --batch
+const (
+	defaultLimit     = 30
+	defaultBatchSize = 1000
+)
+
 func InteractiveQueryBuilder(config *Config) error {
 	// 1. Select time range (FIRST - to use for label queries)
-	timeArgs, err := selectTimeRange()
+	timeArgs, err := selectTimeRange(config)
 	if err != nil {
 		return fmt.Errorf("time range selection failed: %w", err)
 	}
@@ -30,117 +46,359 @@ func InteractiveQueryBuilder(config *Config) error {
 	// Set timeArgs in config for use in label queries
 	config.TimeArgs = timeArgs
 
+	// Fetch label values in the background while the user is answering prompts
+	config.Prefetcher = newPrefetcher(config, config.PrefetchWorkers)
+	defer config.Prefetcher.Close()
+
 	// 2. Select labels
 	selectors, err := selectLabels(config)
 	if err != nil {
 		return fmt.Errorf("label selection failed: %w", err)
 	}
 
-	// 3. Select line filter
-	lineFilter, err := selectLineFilter()
+	// 3. Select line filters
+	lineFilters, err := selectLineFilters()
 	if err != nil {
 		return fmt.Errorf("line filter selection failed: %w", err)
 	}
 
-	// 4. Build command arguments
-	args := buildLogCLIArgs(config.LogCLICmd, selectors, lineFilter, timeArgs)
+	query := Query{Selectors: selectors, LineFilters: lineFilters}
+
+	// Structured metadata is not indexed, so it is filtered like parsed labels
+	query.Metadata, err = selectMetadataFilters(config, query)
+	if err != nil {
+		return fmt.Errorf("metadata filter selection failed: %w", err)
+	}
+
+	// Parser and formatting stages, previewed against sample lines
+	if err := selectPipeline(config, &query); err != nil {
+		return fmt.Errorf("pipeline selection failed: %w", err)
+	}
+
+	// Remember the labels and values used, to rank them first next time
+	config.Usage.Record(query.Selectors)
+	if err := config.Usage.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save usage stats: %v\n", err)
+	}
+
+	// Tailing only reads new lines, there is no time range to scan.
+	// Diagnosing runs the query several times, so it is confirmed too.
+	if config.Estimate && !config.Tail {
+		if err := confirmQueryCost(config, query, timeArgs); err != nil {
+			return err
+		}
+	}
+
+	if config.Diagnose {
+		return diagnoseQuery(config, query, timeArgs)
+	}
+
+	printLintWarnings(query, timeArgs)
+
+	// 4. Select result limit, direction and batch size
+	if config.Export.Path != "" {
+		// Export fetches the whole time range, result options do not apply
+		if err := exportQuery(config.LogCLICmd, query, timeArgs, config.Export); err != nil {
+			return fmt.Errorf("export failed: %w", err)
+		}
+		return nil
+	}
+
+	queryArgs := append([]string{}, timeArgs...)
+	if config.Tail {
+		// Follow new lines instead of querying the discovery time range
+		queryArgs = tailArgs(config.DelayFor)
+	} else {
+		if err := selectResultOptions(config); err != nil {
+			return fmt.Errorf("result option selection failed: %w", err)
+		}
+		queryArgs = append(queryArgs, resultArgs(config.Limit, config.Direction, config.BatchSize)...)
+	}
+
+	// 5. Build command arguments
+	args := buildLogCLIArgs(config.LogCLICmd, query, queryArgs)
+
+	// 6. Build the Grafana Explore link if requested
+	exploreURL := ""
+	if config.ShowExplore || config.OpenBrowser || config.Output == OutputExplore {
+		exploreURL, err = buildExploreURL(config.GrafanaURL, config.DatasourceUID, query.String(), timeArgs)
+		if err != nil {
+			return fmt.Errorf("explore URL generation failed: %w", err)
+		}
+	}
+	if config.OpenBrowser {
+		if err := openBrowser(exploreURL); err != nil {
+			return err
+		}
+	}
 
-	// 5. Execute or output command
+	// 7. Execute or output command
 	if config.Execute {
 		// Execute mode
-		cmd := exec.Command(args[0], args[1:]...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		cmd.Stdin = os.Stdin
-
-		if err := cmd.Run(); err != nil {
+		if config.ShowExplore {
+			fmt.Fprintf(os.Stderr, "Grafana Explore: %s\n", exploreURL)
+		}
+		lines, shown, err := executeQuery(config, args, query.LineFilters)
+		if err != nil {
 			return fmt.Errorf("execution failed: %w", err)
 		}
+		if len(shown) > 0 && isTerminal(os.Stdin) {
+			return showContext(config, query, shown)
+		}
+		if lines == 0 && !config.Tail && isTerminal(os.Stdin) {
+			// Help find out whether the selector, a filter or the time range is to blame
+			diagnose, err := promptForDiagnose()
+			if err != nil {
+				return err
+			}
+			if diagnose {
+				return diagnoseQuery(config, query, timeArgs)
+			}
+		}
 	} else {
 		// Output mode (default)
-		fmt.Println(formatAsShellCommand(args))
+		if err := outputCommand(config, args, timeArgs, exploreURL); err != nil {
+			return fmt.Errorf("output failed: %w", err)
+		}
 	}
 
 	return nil
 }
 
-func selectLabels(config *Config) ([]LabelSelector, error) {
-	selectors := []LabelSelector{}
+// executeQuery runs the query and renders its results in the configured format,
+// returning the number of lines shown. With -context the shown lines are
+// returned as well, to pick one to show the context of.
+func executeQuery(config *Config, args []string, lineFilters []LineFilter) (int, []LogEntry, error) {
+	if config.Tail {
+		renderer, err := newRenderer(os.Stdout, config.Format, config.UTC, lineFilters)
+		if err != nil {
+			return 0, nil, err
+		}
+		return 0, nil, tailQuery(args, renderer)
+	}
 
-	for {
-		// Show current labels
-		showCurrentLabels(selectors)
+	// Only -context needs the shown lines, results can be large
+	lines := 0
+	var shown []LogEntry
+	kept := &shown
+	if config.ContextLines <= 0 {
+		kept = nil
+	}
 
-		// Get available labels
-		availableLabels, err := getAvailableLabels(config, selectors)
+	if config.NoPager || !isTerminal(os.Stdout) {
+		renderer, err := newRenderer(os.Stdout, config.Format, config.UTC, lineFilters)
 		if err != nil {
-			return nil, err
+			return 0, nil, err
 		}
-
-		if len(availableLabels) == 0 {
-			fmt.Println("No more labels available.")
-			break
+		if err := streamLogCLIQuery(args, countEntries(&lines, kept, renderer.Render)); err != nil {
+			return lines, nil, err
 		}
+		return lines, shown, renderer.Flush()
+	}
 
-		// Select one label with operator and value
-		selector, err := selectLabelWithOperatorAndValue(config, availableLabels)
-		if err != nil {
-			return nil, err
+	pager, err := startPager()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	renderer, err := newRenderer(pager, config.Format, config.UTC, lineFilters)
+	if err != nil {
+		_ = pager.Close()
+		return 0, nil, err
+	}
+
+	err = streamLogCLIQuery(args, countEntries(&lines, kept, renderer.Render))
+	if err == nil {
+		err = renderer.Flush()
+	}
+	pagerErr := pager.Close()
+
+	if err != nil && !isPagerClosed(err) {
+		return lines, nil, err
+	}
+	return lines, shown, pagerErr
+}
+
+// countEntries wraps fn, counting the entries passed to it in n
+// and appending them to kept unless it is nil
+func countEntries(n *int, kept *[]LogEntry, fn func(LogEntry) error) func(LogEntry) error {
+	return func(e LogEntry) error {
+		*n++
+		if kept != nil {
+			*kept = append(*kept, e)
 		}
+		return fn(e)
+	}
+}
+
+func selectLabels(config *Config) ([]LabelSelector, error) {
+	selectors := []LabelSelector{}
+	warmed := false
+	adding := true
 
-		selectors = append(selectors, selector)
+	for {
+		if adding {
+			adding = false
+
+			// Get available labels
+			availableLabels, err := getAvailableLabels(config, selectors)
+			if errors.Is(err, errLookupCancelled) {
+				retry, err := promptForRetry()
+				if err != nil {
+					return nil, err
+				}
+				if retry {
+					adding = true
+					continue
+				}
+				if len(selectors) == 0 {
+					return nil, errLookupCancelled
+				}
+			} else if err != nil {
+				return nil, err
+			} else if len(availableLabels) == 0 {
+				fmt.Println("No more labels available.")
+				if len(selectors) == 0 {
+					return selectors, nil
+				}
+			} else {
+				if !warmed {
+					// Warm label values while the user is choosing a label
+					config.Prefetcher.Warm(availableLabels)
+					warmed = true
+				}
+
+				// Select one label with operator and value
+				selector, err := selectLabelWithOperatorAndValue(config, availableLabels)
+				if errors.Is(err, errLookupCancelled) {
+					// Go back to label selection
+					fmt.Println("\nLookup cancelled.")
+					adding = true
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+
+				if slices.Contains(selectors, selector) {
+					fmt.Printf("\n%s is already set.\n", selector)
+				} else {
+					selectors = append(selectors, selector)
+				}
+			}
+		}
 
-		// Ask if more labels needed
-		continueAdding, err := promptForMoreLabels()
+		// Review the selectors before moving on
+		showCurrentLabels(selectors)
+		action, index, err := promptReviewAction(len(selectors), "label")
 		if err != nil {
 			return nil, err
 		}
-		if !continueAdding {
-			break
+
+		switch action {
+		case ActionDone:
+			return selectors, nil
+		case ActionAdd:
+			adding = true
+		case ActionEdit:
+			selector, err := editLabelSelector(config, selectors[index])
+			if errors.Is(err, errLookupCancelled) {
+				fmt.Println("\nLookup cancelled.")
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			selectors[index] = selector
+		case ActionDelete:
+			selectors = append(selectors[:index], selectors[index+1:]...)
+			// A query needs at least one selector
+			adding = len(selectors) == 0
+		case ActionNegate:
+			selectors[index] = selectors[index].Negate()
 		}
 	}
-
-	return selectors, nil
 }
 
 func showCurrentLabels(selectors []LabelSelector) {
 	if len(selectors) > 0 {
 		fmt.Println("\n=== Current labels ===")
-		for _, s := range selectors {
-			fmt.Printf("[SET] %s%s\"%s\"\n", s.Label, s.Operator, s.Value)
+		for i, s := range selectors {
+			fmt.Printf("%d. %s\n", i+1, s)
 		}
 	}
 }
 
+// editLabelSelector asks for a new operator and value for the selector's label
+func editLabelSelector(config *Config, selector LabelSelector) (LabelSelector, error) {
+	config.Prefetcher.Prefetch(selector.Label)
+
+	operator, err := selectOperator(selector.Label)
+	if err != nil {
+		return LabelSelector{}, fmt.Errorf("operator selection failed: %w", err)
+	}
+
+	value, err := selectOrInputValue(config, selector.Label, operator)
+	if err != nil {
+		return LabelSelector{}, fmt.Errorf("value selection failed: %w", err)
+	}
+
+	return LabelSelector{
+		Label:    selector.Label,
+		Operator: operator,
+		Value:    value,
+	}, nil
+}
+
 func getAvailableLabels(config *Config, selectors []LabelSelector) ([]string, error) {
 	// Get all labels
-	labels, err := getLabels(config)
+	labels, err := lookup("Fetching labels...", func(ctx context.Context) ([]string, error) {
+		return getLabels(ctx, config)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get labels: %w", err)
 	}
 
-	// Filter out already selected labels
+	// Pin favorites and frequently used labels to the top
+	labels = rankItems(hideItems(labels, config.HiddenLabels), config.Favorites, config.Usage.LabelCounts())
+
+	return markLabelsInUse(labels, selectors), nil
+}
+
+// inUseSuffix flags labels that already have a matcher in the label list
+const inUseSuffix = " (in use)"
+
+// markLabelsInUse moves labels that already have a matcher to the end of the list
+// and flags them; they stay selectable for queries like {ns=~"prod-.*", ns!="prod-canary"}
+func markLabelsInUse(labels []string, selectors []LabelSelector) []string {
 	selectedLabels := make(map[string]bool)
 	for _, s := range selectors {
 		selectedLabels[s.Label] = true
 	}
 
-	availableLabels := []string{}
+	unused := []string{}
+	used := []string{}
 	for _, label := range labels {
-		if !selectedLabels[label] {
-			availableLabels = append(availableLabels, label)
+		if selectedLabels[label] {
+			used = append(used, label+inUseSuffix)
+		} else {
+			unused = append(unused, label)
 		}
 	}
 
-	return availableLabels, nil
+	return append(unused, used...)
 }
 
 func selectLabelWithOperatorAndValue(config *Config, availableLabels []string) (LabelSelector, error) {
 	// Select label
-	label, err := selectWithFzf(availableLabels, "Select label:")
+	label, err := selectItem(config, availableLabels, "Select label:")
 	if err != nil {
 		return LabelSelector{}, fmt.Errorf("label selection failed: %w", err)
 	}
+	label = strings.TrimSuffix(label, inUseSuffix)
+
+	// Fetch values while the operator is being chosen
+	config.Prefetcher.Prefetch(label)
 
 	// Select operator
 	operator, err := selectOperator(label)
@@ -164,28 +422,31 @@ func selectLabelWithOperatorAndValue(config *Config, availableLabels []string) (
 func selectOrInputValue(config *Config, label string, operator string) (string, error) {
 	if operator == "=" || operator == "!=" {
 		// For equality operators, select from existing values
-		values, err := getLabelValues(config, label)
+		values, err := lookup(fmt.Sprintf("Fetching values for '%s'...", label), func(ctx context.Context) ([]string, error) {
+			return config.Prefetcher.Values(ctx, config, label)
+		})
 		if err != nil {
 			return "", fmt.Errorf("failed to get label values: %w", err)
 		}
-		return selectWithFzf(values, fmt.Sprintf("Select value for '%s':", label))
+		values = rankItems(values, config.FavoriteValues[label], config.Usage.ValueCounts(label))
+		return selectItem(config, values, fmt.Sprintf("Select value for '%s':", label))
 	} else {
 		// For regex operators, input pattern
 		return inputText(fmt.Sprintf("Enter regex pattern for '%s': ", label))
 	}
 }
 
-func promptForMoreLabels() (bool, error) {
-	fmt.Print("\nAdd more labels? (y/N): ")
+func promptForRetry() (bool, error) {
+	fmt.Print("\nLookup cancelled. Retry? (Y/n): ")
 	answer, err := inputText("")
 	if err != nil {
 		return false, err
 	}
 	answer = strings.ToLower(strings.TrimSpace(answer))
-	return answer == "y" || answer == "yes", nil
+	return answer != "n" && answer != "no", nil
 }
 
-func selectLineFilter() (*LineFilter, error) {
+func selectLineFilters() ([]LineFilter, error) {
 	fmt.Print("\nAdd line filter? (y/N): ")
 	answer, err := inputText("")
 	if err != nil {
@@ -197,32 +458,113 @@ func selectLineFilter() (*LineFilter, error) {
 		return nil, nil
 	}
 
+	filters := []LineFilter{}
+	adding := true
+	for {
+		if adding {
+			filter, err := selectLineFilter()
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, filter)
+			adding = false
+		}
+
+		// Review the line filters before moving on
+		showCurrentLineFilters(filters)
+		action, index, err := promptReviewAction(len(filters), "line filter")
+		if err != nil {
+			return nil, err
+		}
+
+		switch action {
+		case ActionDone:
+			return filters, nil
+		case ActionAdd:
+			adding = true
+		case ActionEdit:
+			filter, err := selectLineFilter()
+			if err != nil {
+				return nil, err
+			}
+			filters[index] = filter
+		case ActionDelete:
+			filters = append(filters[:index], filters[index+1:]...)
+			if len(filters) == 0 {
+				return nil, nil
+			}
+		case ActionNegate:
+			filters[index] = filters[index].Negate()
+		}
+	}
+}
+
+func showCurrentLineFilters(filters []LineFilter) {
+	fmt.Println("\n=== Current line filters ===")
+	for i, f := range filters {
+		fmt.Printf("%d. %s\n", i+1, f)
+	}
+}
+
+// selectLineFilter asks for the operator and text of a single line filter
+func selectLineFilter() (LineFilter, error) {
 	// Select line filter operator
 	operator, err := selectLineFilterOperator()
 	if err != nil {
-		return nil, err
+		return LineFilter{}, err
+	}
+
+	switch operator {
+	case filterCaseInsensitive:
+		text, err := inputText("Enter filter text (any case): ")
+		if err != nil {
+			return LineFilter{}, err
+		}
+		return caseInsensitiveFilter(text), nil
+	case filterAnyOf:
+		terms, err := inputText("Enter terms separated by commas: ")
+		if err != nil {
+			return LineFilter{}, err
+		}
+		return anyOfFilter(terms)
+	case filterIP:
+		address, err := inputText("Enter IP address, range or CIDR (e.g. 10.0.0.0/8): ")
+		if err != nil {
+			return LineFilter{}, err
+		}
+		return ipFilter(address)
 	}
 
 	// Input filter text
 	fmt.Print("Enter filter text: ")
 	text, err := inputText("")
 	if err != nil {
-		return nil, err
+		return LineFilter{}, err
 	}
 
-	return &LineFilter{
+	return LineFilter{
 		Operator: operator,
 		Text:     text,
 	}, nil
 }
 
+// Line filter helpers offered next to the raw operators
+const (
+	filterCaseInsensitive = "case-insensitive"
+	filterAnyOf           = "any-of"
+	filterIP              = "ip"
+)
+
 func selectLineFilterOperator() (string, error) {
 	fmt.Println("\nSelect line filter operator (default: 1):")
 	fmt.Println("1. |= (contains)")
 	fmt.Println("2. != (does not contain)")
 	fmt.Println("3. |~ (matches regex)")
 	fmt.Println("4. !~ (does not match regex)")
-	fmt.Print("Enter number (1-4) or press Enter for default: ")
+	fmt.Println("5. contains, ignoring case")
+	fmt.Println("6. contains any of several terms")
+	fmt.Println("7. contains an IP address in a range or CIDR")
+	fmt.Print("Enter number (1-7) or press Enter for default: ")
 
 	choice, err := inputText("")
 	if err != nil {
@@ -234,14 +576,107 @@ func selectLineFilterOperator() (string, error) {
 	}
 
 	num, err := strconv.Atoi(choice)
-	if err != nil || num < 1 || num > 4 {
+	if err != nil || num < 1 || num > 7 {
 		return "", fmt.Errorf("invalid choice: %s", choice)
 	}
 
-	operators := []string{"|=", "!=", "|~", "!~"}
+	operators := []string{"|=", "!=", "|~", "!~", filterCaseInsensitive, filterAnyOf, filterIP}
 	return operators[num-1], nil
 }
 
+func selectResultOptions(config *Config) error {
+	if config.Limit < 0 {
+		limit, err := selectLimit()
+		if err != nil {
+			return err
+		}
+		config.Limit = limit
+	}
+
+	if config.Direction == "" {
+		direction, err := selectDirection()
+		if err != nil {
+			return err
+		}
+		config.Direction = direction
+	}
+
+	// The batch size only matters when more than one batch is fetched
+	if config.BatchSize == 0 && (config.Limit == 0 || config.Limit > defaultBatchSize) {
+		batchSize, err := selectBatchSize()
+		if err != nil {
+			return err
+		}
+		config.BatchSize = batchSize
+	}
+
+	return nil
+}
+
+func selectLimit() (int, error) {
+	fmt.Printf("\nEnter result limit (number or 'all', default: %d): ", defaultLimit)
+	answer, err := inputText("")
+	if err != nil {
+		return 0, err
+	}
+	return parseLimit(answer)
+}
+
+// parseLimit parses a result limit where "all" means no limit (0 for logcli)
+func parseLimit(input string) (int, error) {
+	input = strings.ToLower(strings.TrimSpace(input))
+	switch input {
+	case "":
+		return defaultLimit, nil
+	case "all":
+		return 0, nil
+	}
+
+	limit, err := strconv.Atoi(input)
+	if err != nil || limit < 1 {
+		return 0, fmt.Errorf("invalid limit: %s (expected a positive number or 'all')", input)
+	}
+	return limit, nil
+}
+
+func selectDirection() (string, error) {
+	fmt.Println("\nSelect direction (default: 1):")
+	fmt.Println("1. backward (newest first)")
+	fmt.Println("2. forward (oldest first)")
+	fmt.Print("Enter number (1-2) or press Enter for default: ")
+
+	choice, err := inputText("")
+	if err != nil {
+		return "", err
+	}
+
+	switch choice {
+	case "", "1":
+		return DirectionBackward, nil
+	case "2":
+		return DirectionForward, nil
+	default:
+		return "", fmt.Errorf("invalid choice: %s", choice)
+	}
+}
+
+func selectBatchSize() (int, error) {
+	fmt.Printf("Enter batch size (default: %d): ", defaultBatchSize)
+	answer, err := inputText("")
+	if err != nil {
+		return 0, err
+	}
+	if answer == "" {
+		return defaultBatchSize, nil
+	}
+
+	batchSize, err := strconv.Atoi(answer)
+	if err != nil || batchSize < 1 {
+		return 0, fmt.Errorf("invalid batch size: %s", answer)
+	}
+	return batchSize, nil
+}
+
 func inputText(prompt string) (string, error) {
 	if prompt != "" {
 		fmt.Print(prompt)
@@ -254,27 +689,48 @@ func inputText(prompt string) (string, error) {
 	return strings.TrimSpace(text), nil
 }
 
-func selectTimeRange() ([]string, error) {
+func selectTimeRange(config *Config) ([]string, error) {
+	if config.Tail {
+		// -tail given on the command line, nothing to choose
+		return tailDiscoveryArgs, nil
+	}
+
+	// -export, -diagnose and -context need a time range, live tail has none
+	canTail := tailAllowed(config)
+
 	fmt.Println("Select time range type:")
 	fmt.Println("1. Relative (e.g., 1h, 24h)")
 	fmt.Println("2. Absolute (specific dates)")
-	fmt.Print("Enter choice (1-2): ")
+	if canTail {
+		fmt.Println("3. Live tail (follow new lines)")
+		fmt.Print("Enter choice (1-3): ")
+	} else {
+		fmt.Print("Enter choice (1-2): ")
+	}
 
 	choice, err := inputText("")
 	if err != nil {
 		return nil, err
 	}
 
-	switch choice {
-	case "1":
+	switch {
+	case choice == "1":
 		return selectRelativeTime()
-	case "2":
+	case choice == "2":
 		return selectAbsoluteTime()
+	case choice == "3" && canTail:
+		config.Tail = true
+		return tailDiscoveryArgs, nil
 	default:
 		return nil, fmt.Errorf("invalid choice: %s", choice)
 	}
 }
 
+// tailAllowed reports whether live tail can be combined with the options
+func tailAllowed(config *Config) bool {
+	return config.Export.Path == "" && !config.Diagnose && config.ContextLines == 0
+}
+
 func selectRelativeTime() ([]string, error) {
 	fmt.Print("Enter relative time (e.g., 1h, 24h, 7d): ")
 	duration, err := inputText("")
@@ -336,47 +792,35 @@ func selectOperator(label string) (string, error) {
 	return operators[num-1], nil
 }
 
-func selectWithFzf(items []string, prompt string) (string, error) {
+// selectItem lets the user pick one of items with the configured selector backend
+func selectItem(config *Config, items []string, prompt string) (string, error) {
 	if len(items) == 0 {
 		return "", fmt.Errorf("no items to select")
 	}
+	return config.Selector.Select(items, prompt)
+}
 
-	cmd := exec.Command("fzf", "--prompt", prompt)
-	cmd.Stdin = strings.NewReader(strings.Join(items, "\n"))
-	cmd.Stderr = os.Stderr
-
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("fzf failed: %w", err)
-	}
-
-	selected := strings.TrimSpace(string(output))
-	if selected == "" {
-		return "", fmt.Errorf("no selection made")
-	}
+func buildLogCLIArgs(logcliCmd string, query Query, timeArgs []string) []string {
+	// Build command arguments
+	args := []string{logcliCmd, "query", query.String()}
+	args = append(args, timeArgs...)
 
-	return selected, nil
+	return args
 }
 
-func buildLogCLIArgs(logcliCmd string, selectors []LabelSelector, lineFilter *LineFilter, timeArgs []string) []string {
-	// Build LogQL query
-	query := "{"
-	for i, s := range selectors {
-		if i > 0 {
-			query += ","
-		}
-		query += fmt.Sprintf("%s%s\"%s\"", s.Label, s.Operator, s.Value)
+// resultArgs returns the logcli arguments for the result options,
+// leaving out values that match the logcli defaults
+func resultArgs(limit int, direction string, batchSize int) []string {
+	args := []string{}
+	if limit >= 0 && limit != defaultLimit {
+		args = append(args, "--limit", strconv.Itoa(limit))
 	}
-	query += "}"
-
-	if lineFilter != nil {
-		query += fmt.Sprintf(" %s \"%s\"", lineFilter.Operator, lineFilter.Text)
+	if direction == DirectionForward {
+		args = append(args, "--forward")
+	}
+	if batchSize > 0 && batchSize != defaultBatchSize {
+		args = append(args, "--batch", strconv.Itoa(batchSize))
 	}
-
-	// Build command arguments
-	args := []string{logcliCmd, "query", query}
-	args = append(args, timeArgs...)
-
 	return args
 }
 
@@ -385,9 +829,9 @@ func formatAsShellCommand(args []string) string {
 	quotedArgs := make([]string, len(args))
 	copy(quotedArgs, args)
 
-	// Add single quotes around the query (3rd argument)
+	// Quote the query (3rd argument), it may contain quotes of its own
 	if len(quotedArgs) > 2 {
-		quotedArgs[2] = "'" + quotedArgs[2] + "'"
+		quotedArgs[2] = shellQuote(quotedArgs[2])
 	}
 
 	return strings.Join(quotedArgs, " ")
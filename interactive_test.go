@@ -7,12 +7,12 @@ import (
 
 func TestBuildLogCLIArgs(t *testing.T) {
 	tests := []struct {
-		name       string
-		logcliCmd  string
-		selectors  []LabelSelector
-		lineFilter *LineFilter
-		timeArgs   []string
-		want       []string
+		name        string
+		logcliCmd   string
+		selectors   []LabelSelector
+		lineFilters []LineFilter
+		timeArgs    []string
+		want        []string
 	}{
 		{
 			name:      "single label with equals",
@@ -20,9 +20,9 @@ func TestBuildLogCLIArgs(t *testing.T) {
 			selectors: []LabelSelector{
 				{Label: "app", Operator: "=", Value: "nginx"},
 			},
-			lineFilter: nil,
-			timeArgs:   []string{"--since", "1h"},
-			want:       []string{"logcli", "query", `{app="nginx"}`, "--since", "1h"},
+			lineFilters: nil,
+			timeArgs:    []string{"--since", "1h"},
+			want:        []string{"logcli", "query", `{app="nginx"}`, "--since", "1h"},
 		},
 		{
 			name:      "multiple labels",
@@ -31,9 +31,9 @@ func TestBuildLogCLIArgs(t *testing.T) {
 				{Label: "app", Operator: "=", Value: "nginx"},
 				{Label: "env", Operator: "!=", Value: "test"},
 			},
-			lineFilter: nil,
-			timeArgs:   []string{"--since", "2h"},
-			want:       []string{"logcli", "query", `{app="nginx",env!="test"}`, "--since", "2h"},
+			lineFilters: nil,
+			timeArgs:    []string{"--since", "2h"},
+			want:        []string{"logcli", "query", `{app="nginx",env!="test"}`, "--since", "2h"},
 		},
 		{
 			name:      "with line filter contains",
@@ -41,9 +41,9 @@ func TestBuildLogCLIArgs(t *testing.T) {
 			selectors: []LabelSelector{
 				{Label: "app", Operator: "=", Value: "nginx"},
 			},
-			lineFilter: &LineFilter{Operator: "|=", Text: "error"},
-			timeArgs:   []string{"--since", "1h"},
-			want:       []string{"logcli", "query", `{app="nginx"} |= "error"`, "--since", "1h"},
+			lineFilters: []LineFilter{{Operator: "|=", Text: "error"}},
+			timeArgs:    []string{"--since", "1h"},
+			want:        []string{"logcli", "query", `{app="nginx"} |= "error"`, "--since", "1h"},
 		},
 		{
 			name:      "with line filter not contains",
@@ -51,9 +51,9 @@ func TestBuildLogCLIArgs(t *testing.T) {
 			selectors: []LabelSelector{
 				{Label: "app", Operator: "=", Value: "nginx"},
 			},
-			lineFilter: &LineFilter{Operator: "!=", Text: "debug"},
-			timeArgs:   []string{"--since", "1h"},
-			want:       []string{"logcli", "query", `{app="nginx"} != "debug"`, "--since", "1h"},
+			lineFilters: []LineFilter{{Operator: "!=", Text: "debug"}},
+			timeArgs:    []string{"--since", "1h"},
+			want:        []string{"logcli", "query", `{app="nginx"} != "debug"`, "--since", "1h"},
 		},
 		{
 			name:      "with line filter regex match",
@@ -61,9 +61,9 @@ func TestBuildLogCLIArgs(t *testing.T) {
 			selectors: []LabelSelector{
 				{Label: "app", Operator: "=", Value: "nginx"},
 			},
-			lineFilter: &LineFilter{Operator: "|~", Text: `error|warn`},
-			timeArgs:   []string{"--since", "1h"},
-			want:       []string{"logcli", "query", `{app="nginx"} |~ "error|warn"`, "--since", "1h"},
+			lineFilters: []LineFilter{{Operator: "|~", Text: `error|warn`}},
+			timeArgs:    []string{"--since", "1h"},
+			want:        []string{"logcli", "query", `{app="nginx"} |~ "error|warn"`, "--since", "1h"},
 		},
 		{
 			name:      "with line filter regex not match",
@@ -71,9 +71,29 @@ func TestBuildLogCLIArgs(t *testing.T) {
 			selectors: []LabelSelector{
 				{Label: "app", Operator: "=", Value: "nginx"},
 			},
-			lineFilter: &LineFilter{Operator: "!~", Text: `\.(jpg|png|gif)$`},
-			timeArgs:   []string{"--since", "1h"},
-			want:       []string{"logcli", "query", `{app="nginx"} !~ "\.(jpg|png|gif)$"`, "--since", "1h"},
+			lineFilters: []LineFilter{{Operator: "!~", Text: `\.(jpg|png|gif)$`}},
+			timeArgs:    []string{"--since", "1h"},
+			want:        []string{"logcli", "query", "{app=\"nginx\"} !~ `\\.(jpg|png|gif)$`", "--since", "1h"},
+		},
+		{
+			name:      "multiple line filters",
+			logcliCmd: "logcli",
+			selectors: []LabelSelector{
+				{Label: "app", Operator: "=", Value: "nginx"},
+			},
+			lineFilters: []LineFilter{{Operator: "|=", Text: "error"}, {Operator: "!=", Text: "healthz"}},
+			timeArgs:    []string{"--since", "1h"},
+			want:        []string{"logcli", "query", `{app="nginx"} |= "error" != "healthz"`, "--since", "1h"},
+		},
+		{
+			name:      "multiple matchers for the same label",
+			logcliCmd: "logcli",
+			selectors: []LabelSelector{
+				{Label: "namespace", Operator: "=~", Value: "prod-.*"},
+				{Label: "namespace", Operator: "!=", Value: "prod-canary"},
+			},
+			timeArgs: []string{"--since", "1h"},
+			want:     []string{"logcli", "query", `{namespace=~"prod-.*",namespace!="prod-canary"}`, "--since", "1h"},
 		},
 		{
 			name:      "regex operator",
@@ -81,9 +101,9 @@ func TestBuildLogCLIArgs(t *testing.T) {
 			selectors: []LabelSelector{
 				{Label: "status", Operator: "=~", Value: `5\d{2}`},
 			},
-			lineFilter: nil,
-			timeArgs:   []string{"--since", "1h"},
-			want:       []string{"logcli", "query", `{status=~"5\d{2}"}`, "--since", "1h"},
+			lineFilters: nil,
+			timeArgs:    []string{"--since", "1h"},
+			want:        []string{"logcli", "query", "{status=~`5\\d{2}`}", "--since", "1h"},
 		},
 		{
 			name:      "regex not match operator",
@@ -91,9 +111,9 @@ func TestBuildLogCLIArgs(t *testing.T) {
 			selectors: []LabelSelector{
 				{Label: "path", Operator: "!~", Value: `\.(jpg|png|gif)$`},
 			},
-			lineFilter: nil,
-			timeArgs:   []string{"--since", "1h"},
-			want:       []string{"logcli", "query", `{path!~"\.(jpg|png|gif)$"}`, "--since", "1h"},
+			lineFilters: nil,
+			timeArgs:    []string{"--since", "1h"},
+			want:        []string{"logcli", "query", "{path!~`\\.(jpg|png|gif)$`}", "--since", "1h"},
 		},
 		{
 			name:      "absolute time range",
@@ -101,9 +121,9 @@ func TestBuildLogCLIArgs(t *testing.T) {
 			selectors: []LabelSelector{
 				{Label: "app", Operator: "=", Value: "nginx"},
 			},
-			lineFilter: nil,
-			timeArgs:   []string{"--from", "2025-08-14T00:00:00+09:00", "--to", "2025-08-14T23:59:59+09:00"},
-			want:       []string{"logcli", "query", `{app="nginx"}`, "--from", "2025-08-14T00:00:00+09:00", "--to", "2025-08-14T23:59:59+09:00"},
+			lineFilters: nil,
+			timeArgs:    []string{"--from", "2025-08-14T00:00:00+09:00", "--to", "2025-08-14T23:59:59+09:00"},
+			want:        []string{"logcli", "query", `{app="nginx"}`, "--from", "2025-08-14T00:00:00+09:00", "--to", "2025-08-14T23:59:59+09:00"},
 		},
 		{
 			name:      "custom logcli command",
@@ -111,9 +131,9 @@ func TestBuildLogCLIArgs(t *testing.T) {
 			selectors: []LabelSelector{
 				{Label: "app", Operator: "=", Value: "nginx"},
 			},
-			lineFilter: nil,
-			timeArgs:   []string{"--since", "1h"},
-			want:       []string{"/usr/local/bin/logcli", "query", `{app="nginx"}`, "--since", "1h"},
+			lineFilters: nil,
+			timeArgs:    []string{"--since", "1h"},
+			want:        []string{"/usr/local/bin/logcli", "query", `{app="nginx"}`, "--since", "1h"},
 		},
 		{
 			name:      "no line filter when nil",
@@ -121,15 +141,15 @@ func TestBuildLogCLIArgs(t *testing.T) {
 			selectors: []LabelSelector{
 				{Label: "app", Operator: "=", Value: "test"},
 			},
-			lineFilter: nil,
-			timeArgs:   []string{"--since", "30m"},
-			want:       []string{"logcli", "query", `{app="test"}`, "--since", "30m"},
+			lineFilters: nil,
+			timeArgs:    []string{"--since", "30m"},
+			want:        []string{"logcli", "query", `{app="test"}`, "--since", "30m"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := buildLogCLIArgs(tt.logcliCmd, tt.selectors, tt.lineFilter, tt.timeArgs)
+			got := buildLogCLIArgs(tt.logcliCmd, Query{Selectors: tt.selectors, LineFilters: tt.lineFilters}, tt.timeArgs)
 
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("buildLogCLIArgs() = %v, want %v", got, tt.want)
@@ -159,6 +179,11 @@ func TestFormatAsShellCommand(t *testing.T) {
 			args: []string{"logcli", "query", `{app="nginx",env!="test"} |~ "error|warn"`, "--from", "2025-08-14T00:00:00+09:00", "--to", "2025-08-14T23:59:59+09:00"},
 			want: `logcli query '{app="nginx",env!="test"} |~ "error|warn"' --from 2025-08-14T00:00:00+09:00 --to 2025-08-14T23:59:59+09:00`,
 		},
+		{
+			name: "single quote in a filter",
+			args: []string{"logcli", "query", `{app="nginx"} |= "can't connect"`, "--since", "1h"},
+			want: `logcli query '{app="nginx"} |= "can'\''t connect"' --since 1h`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -170,3 +195,116 @@ func TestFormatAsShellCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{input: "", want: defaultLimit},
+		{input: "all", want: 0},
+		{input: " ALL ", want: 0},
+		{input: "500", want: 500},
+		{input: "0", wantErr: true},
+		{input: "-5", wantErr: true},
+		{input: "many", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseLimit(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLimit(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseLimit(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResultArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int
+		direction string
+		batchSize int
+		want      []string
+	}{
+		{
+			name:      "logcli defaults",
+			limit:     defaultLimit,
+			direction: DirectionBackward,
+			batchSize: defaultBatchSize,
+			want:      []string{},
+		},
+		{
+			name:      "all lines forward",
+			limit:     0,
+			direction: DirectionForward,
+			batchSize: 5000,
+			want:      []string{"--limit", "0", "--forward", "--batch", "5000"},
+		},
+		{
+			name:      "unset options",
+			limit:     -1,
+			direction: "",
+			batchSize: 0,
+			want:      []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resultArgs(tt.limit, tt.direction, tt.batchSize)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resultArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkLabelsInUse(t *testing.T) {
+	labels := []string{"app", "env", "namespace", "pod"}
+	selectors := []LabelSelector{
+		{Label: "namespace", Operator: "=~", Value: "prod-.*"},
+		{Label: "app", Operator: "=", Value: "nginx"},
+	}
+
+	got := markLabelsInUse(labels, selectors)
+	want := []string{"env", "pod", "app (in use)", "namespace (in use)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("markLabelsInUse() = %v, want %v", got, want)
+	}
+}
+
+func TestSelectTimeRangeTail(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		wantTail bool
+	}{
+		{name: "query", config: Config{}, wantTail: true},
+		{name: "export", config: Config{Export: ExportOptions{Path: "out.jsonl"}}},
+		{name: "diagnose", config: Config{Diagnose: true}},
+		{name: "context", config: Config{ContextLines: 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withStdin(t, "3\n")
+			args, err := selectTimeRange(&tt.config)
+			if tt.wantTail {
+				if err != nil || !tt.config.Tail || !reflect.DeepEqual(args, tailDiscoveryArgs) {
+					t.Errorf("selectTimeRange() = %v, %v, tail %v", args, err, tt.config.Tail)
+				}
+				return
+			}
+			// Live tail is not offered, choosing it anyway fails
+			if err == nil || tt.config.Tail {
+				t.Errorf("selectTimeRange() = %v, %v, tail %v, want error", args, err, tt.config.Tail)
+			}
+		})
+	}
+}
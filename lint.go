@@ -0,0 +1,388 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// longRange is the time range above which case-insensitive regexes are flagged
+const longRange = 24 * time.Hour
+
+// LintWarning is a known performance trap found in a query
+type LintWarning struct {
+	Rule    string
+	Message string
+}
+
+func (w LintWarning) String() string {
+	return fmt.Sprintf("[%s] %s", w.Rule, w.Message)
+}
+
+// parsers are the pipeline stages extracting labels from log lines
+var parsers = map[string]bool{"json": true, "logfmt": true, "regexp": true, "pattern": true, "unpack": true}
+
+// stageKeywords are the other pipeline stages; anything else after | is a label filter
+var stageKeywords = map[string]bool{
+	"line_format": true, "label_format": true, "drop": true, "keep": true,
+	"decolorize": true, "unwrap": true,
+}
+
+// logqlToken is a token of a LogQL query; Quoted is set for string literals
+type logqlToken struct {
+	Text   string
+	Quoted bool
+}
+
+// logqlOperators are matched longest first
+var logqlOperators = []string{"|=", "|~", "!=", "!~", "=~", "|", "=", "{", "}", "(", ")", "[", "]", ","}
+
+// tokenizeLogQL splits a query into strings, identifiers and operators
+func tokenizeLogQL(query string) ([]logqlToken, error) {
+	tokens := []logqlToken{}
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '`':
+			end := i + 1
+			for end < len(query) && query[end] != c {
+				if c == '"' && query[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(query) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			text := query[i+1 : end]
+			if c == '"' {
+				unquoted, err := strconv.Unquote(query[i : end+1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid string at offset %d: %w", i, err)
+				}
+				text = unquoted
+			}
+			tokens = append(tokens, logqlToken{Text: text, Quoted: true})
+			i = end + 1
+		default:
+			op := ""
+			for _, candidate := range logqlOperators {
+				if strings.HasPrefix(query[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op != "" {
+				tokens = append(tokens, logqlToken{Text: op})
+				i += len(op)
+				continue
+			}
+
+			end := i
+			for end < len(query) && !strings.ContainsRune(" \t\r\n\"`|!=~{}()[],", rune(query[end])) {
+				end++
+			}
+			if end == i {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+			tokens = append(tokens, logqlToken{Text: query[i:end]})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+// lintStage is a pipeline stage of a parsed query
+type lintStage struct {
+	Parser     string      // Parser name for parser stages
+	LineFilter *LineFilter // Line filter, nil for other stages
+}
+
+// parseLogQLForLint extracts the first stream selector and its pipeline from
+// query, which may be a log or a metric query
+func parseLogQLForLint(query string) ([]LabelSelector, []lintStage, error) {
+	tokens, err := tokenizeLogQL(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	i := 0
+	for i < len(tokens) && (tokens[i].Quoted || tokens[i].Text != "{") {
+		i++
+	}
+	if i == len(tokens) {
+		return nil, nil, fmt.Errorf("no stream selector found")
+	}
+	i++
+
+	selectors := []LabelSelector{}
+	for i < len(tokens) && tokens[i].Text != "}" {
+		if tokens[i].Text == "," {
+			i++
+			continue
+		}
+		if i+2 >= len(tokens) || !tokens[i+2].Quoted {
+			return nil, nil, fmt.Errorf("invalid label matcher near %q", tokens[i].Text)
+		}
+		op := tokens[i+1].Text
+		if op != "=" && op != "!=" && op != "=~" && op != "!~" {
+			return nil, nil, fmt.Errorf("invalid matcher operator %q", op)
+		}
+		selectors = append(selectors, LabelSelector{Label: tokens[i].Text, Operator: op, Value: tokens[i+2].Text})
+		i += 3
+	}
+	if i == len(tokens) {
+		return nil, nil, fmt.Errorf("unterminated stream selector")
+	}
+	i++
+
+	stages := []lintStage{}
+	for ; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.Quoted {
+			continue
+		}
+		switch t.Text {
+		case "|=", "|~", "!=", "!~":
+			if i+1 < len(tokens) && tokens[i+1].Quoted {
+				stages = append(stages, lintStage{LineFilter: &LineFilter{Operator: t.Text, Text: tokens[i+1].Text}})
+				i++
+			} else if i+2 < len(tokens) && tokens[i+1].Text == "ip" && tokens[i+2].Text == "(" {
+				// Skip ip("..."), its ) does not end the log query
+				for i < len(tokens) && (tokens[i].Quoted || tokens[i].Text != ")") {
+					i++
+				}
+			}
+		case "|":
+			if i+1 >= len(tokens) || tokens[i+1].Quoted {
+				continue
+			}
+			next := tokens[i+1].Text
+			switch {
+			case parsers[next]:
+				stages = append(stages, lintStage{Parser: next})
+				i++
+			case stageKeywords[next]:
+				i++
+			default:
+				// Skip label filters, their != and !~ are not line filters
+				i = skipLabelFilter(tokens, i+1) - 1
+			}
+		case "[", ")":
+			// End of the log query inside a metric query
+			return selectors, stages, nil
+		}
+	}
+	return selectors, stages, nil
+}
+
+// skipLabelFilter returns the index after the label filter expression
+// starting at i, e.g. level != "debug" or status >= 500 and method="GET"
+func skipLabelFilter(tokens []logqlToken, i int) int {
+	for i < len(tokens) {
+		// Label name
+		i++
+
+		// Comparison operator, > and < are tokenized as identifiers
+		for i < len(tokens) && !tokens[i].Quoted && (strings.Trim(tokens[i].Text, "<>") == "" ||
+			tokens[i].Text == "=" || tokens[i].Text == "!=" || tokens[i].Text == "=~" || tokens[i].Text == "!~") {
+			i++
+		}
+
+		// Value, possibly a function call like ip("10.0.0.0/8")
+		if i < len(tokens) {
+			i++
+			if i < len(tokens) && !tokens[i].Quoted && tokens[i].Text == "(" {
+				for i < len(tokens) && (tokens[i].Quoted || tokens[i].Text != ")") {
+					i++
+				}
+				i++
+			}
+		}
+
+		if i < len(tokens) && !tokens[i].Quoted && (tokens[i].Text == "and" || tokens[i].Text == "or" || tokens[i].Text == ",") {
+			i++
+			continue
+		}
+		return i
+	}
+	return i
+}
+
+// matchesEverything reports whether a matcher selects all streams,
+// or all streams having the label
+func matchesEverything(s LabelSelector) bool {
+	switch s.Operator {
+	case "=~":
+		return s.Value == ".*" || s.Value == ".+" || s.Value == ""
+	case "!=", "!~":
+		return s.Value == ""
+	}
+	return false
+}
+
+// isLiteralRegex reports whether pattern matches only itself
+func isLiteralRegex(pattern string) bool {
+	return pattern != "" && regexp.QuoteMeta(pattern) == pattern
+}
+
+// lintQuery checks a LogQL query for known performance traps
+// queryRange is the time range it runs over, 0 if unknown
+func lintQuery(query string, queryRange time.Duration) ([]LintWarning, error) {
+	selectors, stages, err := parseLogQLForLint(query)
+	if err != nil {
+		return nil, err
+	}
+
+	warnings := []LintWarning{}
+	positive, equality, regex := false, false, false
+	for _, s := range selectors {
+		if matchesEverything(s) {
+			warnings = append(warnings, LintWarning{"match-all-matcher",
+				fmt.Sprintf("%s matches every stream, remove it", s)})
+			continue
+		}
+		switch s.Operator {
+		case "=":
+			if s.Value != "" {
+				positive, equality = true, true
+			}
+		case "=~":
+			positive, regex = true, true
+		}
+	}
+
+	switch {
+	case !positive:
+		warnings = append(warnings, LintWarning{"missing-stream-labels",
+			"the selector does not narrow down the streams, add a label matcher like app=\"...\""})
+	case regex && !equality:
+		warnings = append(warnings, LintWarning{"regex-only-selector",
+			"the selector only has regex matchers, add an equality matcher so Loki can use its index"})
+	}
+
+	parserSeen := ""
+	for _, stage := range stages {
+		if stage.Parser != "" {
+			if parserSeen == "" {
+				parserSeen = stage.Parser
+			}
+			continue
+		}
+
+		f := stage.LineFilter
+		if parserSeen != "" {
+			warnings = append(warnings, LintWarning{"filter-after-parser",
+				fmt.Sprintf("line filter %s runs after the %s parser, move it before the parser to skip parsing lines it drops", f, parserSeen)})
+		}
+		if (f.Operator == "|~" || f.Operator == "!~") && isLiteralRegex(f.Text) {
+			literal := LineFilter{Operator: "|=", Text: f.Text}
+			if f.Operator == "!~" {
+				literal.Operator = "!="
+			}
+			warnings = append(warnings, LintWarning{"literal-regex",
+				fmt.Sprintf("%s has no regex syntax, use %s instead", f, literal)})
+		}
+		if (f.Operator == "|~" || f.Operator == "!~") && strings.HasPrefix(f.Text, "(?i)") && queryRange > longRange {
+			warnings = append(warnings, LintWarning{"case-insensitive-regex",
+				fmt.Sprintf("case-insensitive regex %s over %s is expensive, narrow the time range or use a case-sensitive filter", f, apiDuration(queryRange))})
+		}
+	}
+
+	return warnings, nil
+}
+
+// printLintWarnings shows the warnings found in the interactive flow
+func printLintWarnings(query Query, timeArgs []string) {
+	start, end, err := resolveTimeRange(timeArgs, time.Now())
+	queryRange := end.Sub(start)
+	if err != nil {
+		queryRange = 0
+	}
+
+	warnings, err := lintQuery(query.String(), queryRange)
+	if err != nil {
+		return
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+}
+
+const lintUsage = `Usage:
+  loqui lint [options] '<query>' ...
+
+Checks LogQL queries for known performance traps and exits with status 1
+when a warning is found. Queries are read from stdin, one per line, when
+none are given.
+
+Options:
+  -range       Time range the queries run over, e.g. 24h or 7d,
+               for range dependent checks (default: unknown)
+`
+
+// runLint implements the lint subcommand and returns the exit status
+func runLint(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, lintUsage)
+	}
+	rangeArg := flags.String("range", "", "Time range the queries run over")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	var queryRange time.Duration
+	if *rangeArg != "" {
+		d, err := parseSince(*rangeArg)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: invalid range: %v\n", err)
+			return 2
+		}
+		queryRange = d
+	}
+
+	queries := flags.Args()
+	if len(queries) == 0 {
+		scanner := bufio.NewScanner(stdin)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				queries = append(queries, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 2
+		}
+	}
+
+	status := 0
+	for _, query := range queries {
+		warnings, err := lintQuery(query, queryRange)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: error: %v\n", query, err)
+			status = 2
+			continue
+		}
+		for _, w := range warnings {
+			fmt.Fprintf(stdout, "%s: %s\n", query, w)
+		}
+		if len(warnings) > 0 && status == 0 {
+			status = 1
+		}
+	}
+	return status
+}
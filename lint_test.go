@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLintQuery(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		queryRange time.Duration
+		want       []string
+		wantErr    bool
+	}{
+		{
+			name:  "clean query",
+			query: `{app="nginx",env=~"prod.*"} |= "error" | json | level="error"`,
+			want:  []string{},
+		},
+		{
+			name:  "match all selector",
+			query: `{app=~".*"}`,
+			want:  []string{"match-all-matcher", "missing-stream-labels"},
+		},
+		{
+			name:  "empty selector",
+			query: `{}`,
+			want:  []string{"missing-stream-labels"},
+		},
+		{
+			name:  "negative matchers only",
+			query: `{app!="nginx"}`,
+			want:  []string{"missing-stream-labels"},
+		},
+		{
+			name:  "regex only",
+			query: `{namespace=~"prod-.*",app!="canary"}`,
+			want:  []string{"regex-only-selector"},
+		},
+		{
+			name:  "literal regex filters",
+			query: `{app="nginx"} |~ "timeout" !~ "healthz" |~ "error|warn"`,
+			want:  []string{"literal-regex", "literal-regex"},
+		},
+		{
+			name:       "case-insensitive regex on a long range",
+			query:      `{app="nginx"} |~ "(?i)error"`,
+			queryRange: 7 * 24 * time.Hour,
+			want:       []string{"case-insensitive-regex"},
+		},
+		{
+			name:       "case-insensitive regex on a short range",
+			query:      `{app="nginx"} |~ "(?i)error"`,
+			queryRange: time.Hour,
+			want:       []string{},
+		},
+		{
+			name:  "line filter after parser",
+			query: `{app="nginx"} | logfmt | status >= 500 and level != "debug" != "healthz"`,
+			want:  []string{"filter-after-parser"},
+		},
+		{
+			name:  "label filter is not a line filter",
+			query: `{app="nginx"} | json | level !~ "debug"`,
+			want:  []string{},
+		},
+		{
+			name:  "ip filter before parser",
+			query: `{app="nginx"} |= ip("10.0.0.0/8") | json |= "timeout"`,
+			want:  []string{"filter-after-parser"},
+		},
+		{
+			name:  "metric query",
+			query: `sum by (level) (count_over_time({app=~".+"} |~ "error" [5m]))`,
+			want:  []string{"match-all-matcher", "missing-stream-labels", "literal-regex"},
+		},
+		{
+			name:    "no selector",
+			query:   `rate(x[5m])`,
+			wantErr: true,
+		},
+		{
+			name:    "unterminated string",
+			query:   `{app="nginx}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, err := lintQuery(tt.query, tt.queryRange)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lintQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			rules := []string{}
+			for _, w := range warnings {
+				rules = append(rules, w.Rule)
+			}
+			if !reflect.DeepEqual(rules, tt.want) {
+				t.Errorf("lintQuery() rules = %v, want %v", rules, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunLint(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if status := runLint([]string{`{app="nginx"} |= "error"`}, nil, &stdout, &stderr); status != 0 {
+		t.Errorf("runLint() clean query status = %d, output %q", status, stdout.String())
+	}
+
+	stdout.Reset()
+	status := runLint([]string{"-range", "7d"}, strings.NewReader("{app=\"nginx\"}\n\n{app=~\".*\"}\n"), &stdout, &stderr)
+	if status != 1 {
+		t.Errorf("runLint() status = %d, want 1", status)
+	}
+	if !strings.Contains(stdout.String(), `{app=~".*"}: [match-all-matcher]`) {
+		t.Errorf("runLint() output = %q", stdout.String())
+	}
+
+	if status := runLint([]string{"not a query"}, nil, &stdout, &stderr); status != 2 {
+		t.Errorf("runLint() invalid query status = %d, want 2", status)
+	}
+	if status := runLint([]string{"-range", "soon", "{}"}, nil, &stdout, &stderr); status != 2 {
+		t.Errorf("runLint() invalid range status = %d, want 2", status)
+	}
+}
@@ -2,25 +2,45 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
 )
 
-// getLabels retrieves available labels from Loki via logcli
-func getLabels(config *Config) ([]string, error) {
-	return getLabelsFromLogCLI(config.LogCLICmd, config.TimeArgs)
+// getLabels retrieves available labels from Loki via logcli, using the cache when possible
+func getLabels(ctx context.Context, config *Config) ([]string, error) {
+	key := config.Cache.Key(config.LokiAddr, config.OrgID, config.TimeArgs, "labels")
+	if labels, ok := config.Cache.Get(key); ok {
+		return labels, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout(config))
+	defer cancel()
+
+	labels, err := getLabelsFromLogCLI(ctx, config.LogCLICmd, config.TimeArgs)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("logcli labels timed out after %s: %w", lookupTimeout(config), ctx.Err())
+		}
+		return nil, err
+	}
+
+	config.Cache.Put(key, labels)
+	return labels, nil
 }
 
 // getLabelsFromLogCLI executes logcli to get labels
-func getLabelsFromLogCLI(logcliCmd string, timeArgs []string) ([]string, error) {
+func getLabelsFromLogCLI(ctx context.Context, logcliCmd string, timeArgs []string) ([]string, error) {
 	args := []string{"labels", "--quiet"}
 	args = append(args, timeArgs...)
 
-	cmd := exec.Command(logcliCmd, args...)
+	cmd := exec.CommandContext(ctx, logcliCmd, args...)
+	isolateFromTerminal(cmd)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return nil, fmt.Errorf("logcli labels failed: %w\nOutput: %s", err, string(output))
+		return nil, newLogCLIError("labels", err, string(output))
 	}
 
 	return parseLabelsOutput(string(output))
@@ -44,20 +64,38 @@ func parseLabelsOutput(output string) ([]string, error) {
 	return labels, nil
 }
 
-// getLabelValues retrieves values for a specific label
-func getLabelValues(config *Config, label string) ([]string, error) {
-	return getLabelValuesFromLogCLI(config.LogCLICmd, label, config.TimeArgs)
+// getLabelValues retrieves values for a specific label, using the cache when possible
+func getLabelValues(ctx context.Context, config *Config, label string) ([]string, error) {
+	key := config.Cache.Key(config.LokiAddr, config.OrgID, config.TimeArgs, "values:"+label)
+	if values, ok := config.Cache.Get(key); ok {
+		return values, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout(config))
+	defer cancel()
+
+	values, err := getLabelValuesFromLogCLI(ctx, config.LogCLICmd, label, config.TimeArgs)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("logcli labels %s timed out after %s: %w", label, lookupTimeout(config), ctx.Err())
+		}
+		return nil, err
+	}
+
+	config.Cache.Put(key, values)
+	return values, nil
 }
 
 // getLabelValuesFromLogCLI executes logcli to get label values
-func getLabelValuesFromLogCLI(logcliCmd string, label string, timeArgs []string) ([]string, error) {
+func getLabelValuesFromLogCLI(ctx context.Context, logcliCmd string, label string, timeArgs []string) ([]string, error) {
 	args := []string{"labels", label, "--quiet"}
 	args = append(args, timeArgs...)
 
-	cmd := exec.Command(logcliCmd, args...)
+	cmd := exec.CommandContext(ctx, logcliCmd, args...)
+	isolateFromTerminal(cmd)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return nil, fmt.Errorf("logcli labels %s failed: %w\nOutput: %s", label, err, string(output))
+		return nil, newLogCLIError("labels "+label, err, string(output))
 	}
 
 	return parseLabelValuesOutput(string(output))
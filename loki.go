@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Loki API endpoints
+const (
+	indexStatsPath     = "/loki/api/v1/index/stats"     // Estimates what a selector scans
+	detectedFieldsPath = "/loki/api/v1/detected_fields" // Lists fields found in matching lines
+	seriesPath         = "/loki/api/v1/series"          // Lists the streams matching a selector
+)
+
+// lokiClient calls the Loki HTTP API directly for requests logcli has no
+// machine-readable output for, using the same environment variables as logcli
+type lokiClient struct {
+	addr        string
+	orgID       string
+	username    string
+	password    string
+	bearerToken string
+	http        *http.Client
+	err         error // Invalid TLS or proxy settings, returned by every request
+}
+
+// newLokiClient creates a client for addr with credentials, TLS and proxy
+// settings from getenv
+func newLokiClient(addr string, getenv func(string) string) *lokiClient {
+	transport, err := lokiTransport(getenv)
+	return &lokiClient{
+		addr:        strings.TrimRight(addr, "/"),
+		orgID:       getenv("LOKI_ORG_ID"),
+		username:    getenv("LOKI_USERNAME"),
+		password:    getenv("LOKI_PASSWORD"),
+		bearerToken: getenv("LOKI_BEARER_TOKEN"),
+		http:        &http.Client{Transport: transport},
+		err:         err,
+	}
+}
+
+// lokiTransport builds the HTTP transport from logcli's TLS and proxy
+// variables. Without LOKI_HTTP_PROXY_URL the usual proxy variables apply.
+func lokiTransport(getenv func(string) string) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	tlsConfig := &tls.Config{
+		ServerName:         getenv("LOKI_TLS_SERVER_NAME"),
+		InsecureSkipVerify: getenv("LOKI_TLS_SKIP_VERIFY") == "true",
+	}
+	if path := getenv("LOKI_CA_CERT_PATH"); path != "" {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return transport, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return transport, fmt.Errorf("no certificates found in %s", path)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	certPath, keyPath := getenv("LOKI_CLIENT_CERT_PATH"), getenv("LOKI_CLIENT_KEY_PATH")
+	if certPath != "" || keyPath != "" {
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return transport, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	if proxy := getenv("LOKI_HTTP_PROXY_URL"); proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return transport, fmt.Errorf("invalid LOKI_HTTP_PROXY_URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return transport, nil
+}
+
+// get requests path with params and decodes the JSON response into v
+func (c *lokiClient) get(ctx context.Context, path string, params url.Values, v any) error {
+	if c.err != nil {
+		return c.err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.addr+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if c.orgID != "" {
+		req.Header.Set("X-Scope-OrgID", c.orgID)
+	}
+	if c.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	} else if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return newLokiAPIError(path, "", "", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return newLokiAPIError(path, resp.Status, string(body), nil)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("%s: failed to decode response: %w", path, err)
+	}
+	return nil
+}
+
+// IndexStats is Loki's estimate of the data a selector covers in a time range
+type IndexStats struct {
+	Streams int64 `json:"streams"`
+	Chunks  int64 `json:"chunks"`
+	Entries int64 `json:"entries"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// IndexStats returns the index statistics for the stream selector of query
+func (c *lokiClient) IndexStats(ctx context.Context, query string, start, end time.Time) (IndexStats, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", start.Format(time.RFC3339Nano))
+	params.Set("end", end.Format(time.RFC3339Nano))
+
+	var stats IndexStats
+	err := c.get(ctx, indexStatsPath, params, &stats)
+	return stats, err
+}
+
+// DetectedField is a field Loki found in the lines matching a query
+// Structured metadata has no parsers, parsed fields name the parsers finding them
+type DetectedField struct {
+	Label       string   `json:"label"`
+	Type        string   `json:"type"`
+	Cardinality int64    `json:"cardinality"`
+	Parsers     []string `json:"parsers"`
+}
+
+// DetectedFields returns the fields found in the lines matching query
+func (c *lokiClient) DetectedFields(ctx context.Context, query string, start, end time.Time) ([]DetectedField, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", start.Format(time.RFC3339Nano))
+	params.Set("end", end.Format(time.RFC3339Nano))
+
+	var response struct {
+		Fields []DetectedField `json:"fields"`
+	}
+	err := c.get(ctx, detectedFieldsPath, params, &response)
+	return response.Fields, err
+}
+
+// Series returns the label sets of the streams matching the selector
+func (c *lokiClient) Series(ctx context.Context, selector string, start, end time.Time) ([]map[string]string, error) {
+	params := url.Values{}
+	params.Set("match[]", selector)
+	params.Set("start", start.Format(time.RFC3339Nano))
+	params.Set("end", end.Format(time.RFC3339Nano))
+
+	var response struct {
+		Data []map[string]string `json:"data"`
+	}
+	err := c.get(ctx, seriesPath, params, &response)
+	return response.Data, err
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLokiClientIndexStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != indexStatsPath {
+			t.Errorf("path = %s, want %s", r.URL.Path, indexStatsPath)
+		}
+		if got := r.URL.Query().Get("query"); got != `{app="nginx"}` {
+			t.Errorf("query = %s", got)
+		}
+		if got := r.URL.Query().Get("start"); got != "2025-08-14T00:00:00Z" {
+			t.Errorf("start = %s", got)
+		}
+		if got := r.Header.Get("X-Scope-OrgID"); got != "tenant-a" {
+			t.Errorf("X-Scope-OrgID = %s", got)
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "alice" || pass != "secret" {
+			t.Errorf("basic auth = %s:%s %v", user, pass, ok)
+		}
+		w.Write([]byte(`{"streams":12,"chunks":40,"entries":5000,"bytes":2048}`))
+	}))
+	defer server.Close()
+
+	env := map[string]string{"LOKI_ORG_ID": "tenant-a", "LOKI_USERNAME": "alice", "LOKI_PASSWORD": "secret"}
+	client := newLokiClient(server.URL+"/", func(key string) string { return env[key] })
+
+	start := time.Date(2025, 8, 14, 0, 0, 0, 0, time.UTC)
+	stats, err := client.IndexStats(context.Background(), `{app="nginx"}`, start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("IndexStats() error = %v", err)
+	}
+	want := IndexStats{Streams: 12, Chunks: 40, Entries: 5000, Bytes: 2048}
+	if stats != want {
+		t.Errorf("IndexStats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestLokiClientError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Authorization = %s", r.Header.Get("Authorization"))
+		}
+		http.Error(w, "no org id", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := newLokiClient(server.URL, func(key string) string {
+		if key == "LOKI_BEARER_TOKEN" {
+			return "token"
+		}
+		return ""
+	})
+	_, err := client.IndexStats(context.Background(), `{app="nginx"}`, time.Now().Add(-time.Hour), time.Now())
+	if !errors.Is(err, ErrTenantMissing) {
+		t.Errorf("IndexStats() error = %v, want %v", err, ErrTenantMissing)
+	}
+	if errorHint(err) == "" {
+		t.Error("errorHint() is empty for a classified API error")
+	}
+}
+
+func TestLokiClientErrorKinds(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusForbidden, ErrForbidden},
+		{http.StatusTooManyRequests, ErrRateLimited},
+	}
+
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+		client := newLokiClient(server.URL, func(string) string { return "" })
+		_, err := client.Series(context.Background(), `{app="nginx"}`, time.Now().Add(-time.Hour), time.Now())
+		server.Close()
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Series() error for %d = %v, want %v", tt.status, err, tt.want)
+		}
+	}
+
+	// Nothing listening
+	client := newLokiClient("http://127.0.0.1:1", func(string) string { return "" })
+	_, err := client.Series(context.Background(), `{app="nginx"}`, time.Now().Add(-time.Hour), time.Now())
+	if !errors.Is(err, ErrConnectionRefused) {
+		t.Errorf("Series() error = %v, want %v", err, ErrConnectionRefused)
+	}
+}
+
+func TestLokiClientDetectedFields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != detectedFieldsPath {
+			t.Errorf("path = %s, want %s", r.URL.Path, detectedFieldsPath)
+		}
+		if got := r.URL.Query().Get("query"); got != `{app="nginx"} |= "error"` {
+			t.Errorf("query = %s", got)
+		}
+		w.Write([]byte(`{"fields":[
+			{"label":"trace_id","type":"string","cardinality":812,"parsers":null},
+			{"label":"status","type":"int","cardinality":4,"parsers":["logfmt"]}
+		],"limit":1000}`))
+	}))
+	defer server.Close()
+
+	client := newLokiClient(server.URL, func(string) string { return "" })
+	start := time.Date(2025, 8, 14, 0, 0, 0, 0, time.UTC)
+	fields, err := client.DetectedFields(context.Background(), `{app="nginx"} |= "error"`, start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("DetectedFields() error = %v", err)
+	}
+	want := []DetectedField{
+		{Label: "trace_id", Type: "string", Cardinality: 812},
+		{Label: "status", Type: "int", Cardinality: 4, Parsers: []string{"logfmt"}},
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("DetectedFields() = %+v, want %+v", fields, want)
+	}
+}
+
+func TestLokiClientTLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"success","data":[{"app":"nginx"}]}`))
+	}))
+	// The rejected handshake is expected, keep it out of the test output
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caPath, ca, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{name: "unknown CA", env: map[string]string{}, wantErr: true},
+		{name: "CA certificate", env: map[string]string{"LOKI_CA_CERT_PATH": caPath}},
+		{name: "skip verify", env: map[string]string{"LOKI_TLS_SKIP_VERIFY": "true"}},
+		{name: "missing CA file", env: map[string]string{"LOKI_CA_CERT_PATH": caPath + ".missing"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newLokiClient(server.URL, func(key string) string { return tt.env[key] })
+			_, err := client.Series(context.Background(), `{app="nginx"}`, time.Now().Add(-time.Hour), time.Now())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Series() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLokiClientProxy(t *testing.T) {
+	var requested string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.String()
+		w.Write([]byte(`{"status":"success","data":[]}`))
+	}))
+	defer proxy.Close()
+
+	client := newLokiClient("http://loki.example:3100", func(key string) string {
+		if key == "LOKI_HTTP_PROXY_URL" {
+			return proxy.URL
+		}
+		return ""
+	})
+	if _, err := client.Series(context.Background(), `{app="nginx"}`, time.Now().Add(-time.Hour), time.Now()); err != nil {
+		t.Fatalf("Series() error = %v", err)
+	}
+	if !strings.HasPrefix(requested, "http://loki.example:3100/loki/api/v1/series?") {
+		t.Errorf("proxy got %s", requested)
+	}
+}
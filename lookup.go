@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultLookupTimeout bounds a single logcli discovery call
+const defaultLookupTimeout = 30 * time.Second
+
+// Retry settings for transient discovery failures
+const (
+	lookupAttempts       = 3
+	lookupInitialBackoff = 500 * time.Millisecond
+)
+
+// errLookupCancelled is returned when the user interrupts a lookup with Ctrl-C
+var errLookupCancelled = errors.New("lookup cancelled")
+
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// lookup runs a discovery call in the foreground. It shows a status line while
+// waiting, cancels the call (but not loqui) on Ctrl-C, and retries transient
+// failures with exponential backoff.
+func lookup[T any](status string, fn func(ctx context.Context) (T, error)) (T, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Ctrl-C only cancels this lookup while it is running
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	defer signal.Stop(interrupted)
+	go func() {
+		select {
+		case <-interrupted:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	stop := startSpinner(status)
+	defer stop()
+
+	var zero T
+	backoff := lookupInitialBackoff
+	for attempt := 1; ; attempt++ {
+		result, err := fn(ctx)
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return zero, errLookupCancelled
+		}
+		if attempt == lookupAttempts || !isTransient(err) {
+			return zero, err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return zero, errLookupCancelled
+		}
+		backoff *= 2
+	}
+}
+
+// startSpinner shows an animated status line on stderr until the returned
+// function is called. Nothing is shown when stderr is not a terminal, or for
+// lookups finishing before the first frame (e.g. cache hits).
+func startSpinner(status string) func() {
+	if !isTerminal(os.Stderr) {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+
+		shown := false
+		for frame := 0; ; frame++ {
+			select {
+			case <-done:
+				if shown {
+					// Clear the status line
+					fmt.Fprintf(os.Stderr, "\r%s\r", strings.Repeat(" ", len(status)+2))
+				}
+				return
+			case <-ticker.C:
+				fmt.Fprintf(os.Stderr, "\r%s %s", spinnerFrames[frame%len(spinnerFrames)], status)
+				shown = true
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// lookupTimeout returns the configured timeout for a single discovery call
+func lookupTimeout(config *Config) time.Duration {
+	if config.LookupTimeout <= 0 {
+		return defaultLookupTimeout
+	}
+	return config.LookupTimeout
+}
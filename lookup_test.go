@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLookupRetriesTransientErrors(t *testing.T) {
+	calls := 0
+	got, err := lookup("test", func(ctx context.Context) (string, error) {
+		calls++
+		if calls == 1 {
+			return "", errors.New("connection refused")
+		}
+		return "ok", nil
+	})
+	if err != nil || got != "ok" {
+		t.Errorf("lookup() = %q, %v, want ok", got, err)
+	}
+	if calls != 2 {
+		t.Errorf("fn called %d times, want 2", calls)
+	}
+}
+
+func TestLookupDoesNotRetryPermanentErrors(t *testing.T) {
+	calls := 0
+	_, err := lookup("test", func(ctx context.Context) ([]string, error) {
+		calls++
+		return nil, errors.New("401 Unauthorized")
+	})
+	if err == nil {
+		t.Error("lookup() succeeded, want error")
+	}
+	if calls != 1 {
+		t.Errorf("fn called %d times, want 1", calls)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 )
 
 // Populated at build time via goreleaser ldflags (-X main.version, etc.)
@@ -17,15 +19,83 @@ const usage = `loqui - Interactive Loki Query Builder
 
 Usage:
   loqui [options]
+  loqui lint [options] '<query>' ...
+  loqui trace [options] <id>
+  loqui series [options] <matcher> ...
+  loqui stats labels [options] [<matcher> ...]
+
+Commands:
+  lint         Check LogQL queries for performance traps, exit status 1
+               on warnings (see loqui lint -help)
+  trace        Print every line mentioning a trace ID across streams,
+               oldest first (see loqui trace -help)
+  series       List the streams matching label matchers with counts per
+               label, or pick one as a selector (see loqui series -help)
+  stats labels Report distinct values, series and top values of each
+               label as a table or JSON (see loqui stats -help)
 
 Options:
   -help        Show this help message
   -version     Show version
+  -config      Configuration file
+               (default: ~/.config/loqui/config.json)
+  -selector    Fuzzy finder: auto, fzf, sk, peco, gum, builtin
+               (default: auto, the first installed finder or builtin)
   -exec        Execute the command immediately
+  -format      Output format for -exec: default, raw, jsonl, csv, color
+               (default: default)
+  -utc         Show timestamps in UTC instead of local time (-exec only)
+  -output      What to print (default: logcli):
+                 logcli   logcli command
+                 explore  Grafana Explore URL
+                 curl     curl command against the query_range API
+                 url      URL-encoded query_range API URL
+                 json     JSON description of the query and time range
+                 logql    bare LogQL query
+  -explore     Also print a Grafana Explore URL (on stderr)
+  -open        Open the Grafana Explore URL in the browser
+  -grafana-url Grafana base URL (default: $GRAFANA_URL)
+  -datasource  Grafana Loki datasource UID (default: $GRAFANA_DATASOURCE_UID)
+  -limit       Maximum number of lines, 0 for all (default: ask)
+  -direction   Result order: backward or forward (default: ask)
+  -batch       Lines fetched per request when the limit exceeds it
+               (default: ask, 1000)
+  -no-pager    Do not page -exec results in a terminal
+  -tail        Follow new log lines instead of querying a time range
+  -delay-for   Seconds to delay tailed lines so late entries are ordered
+               (default: 0)
+  -context     Lines shown before and after a result picked after -exec,
+               from the same stream and unfiltered (default: 0, off)
+  -diagnose    Count the lines left by each matcher and line filter
+               instead of running the query
+  -no-estimate Do not estimate the data a query scans before running
+               or printing it
+  -refresh     Ignore cached labels and values and fetch them again
+  -cache-ttl   How long discovered labels and values are reused
+               (default: 5m, 0 disables the cache)
+  -timeout     Timeout for a single label lookup, retried on transient
+               errors; Ctrl-C cancels a running lookup (default: 30s)
+  -prefetch    Number of label value lookups run in the background
+               (default: 4, 0 disables warming of common labels)
+  -export      Write all results in the time range to a file
+               (gzip compressed when the name ends in .gz)
+  -chunk       Time range fetched per logcli call when exporting
+               (default: 1h)
+  -parallel    Number of chunks fetched concurrently (default: 4)
+  -split-time  Write one export file per chunk
+  -split-labels
+               Write one export file per value of these labels
+               (comma separated, e.g. app,env)
 
 Environment:
   LOKI_ADDR    Loki server address (required)
                Example: http://localhost:3100
+  LOQUI_SELECTOR
+               Fuzzy finder, overridden by -selector
+  GRAFANA_URL  Grafana base URL for Explore links
+               Example: https://grafana.example.com
+  GRAFANA_DATASOURCE_UID
+               UID of the Loki datasource in Grafana
 
 Examples:
   # Set Loki address and run interactive query building
@@ -34,24 +104,157 @@ Examples:
 
   # Execute query immediately
   loqui -exec
+
+  # Execute and highlight the line filter match and log levels
+  loqui -exec -format color
+
+  # Print a Grafana Explore link instead of the command
+  loqui -output explore
+
+  # Print the equivalent curl command
+  loqui -output curl
+
+  # Fetch every line in chronological order
+  loqui -limit 0 -direction forward
+
+  # Export a day of logs as gzipped JSONL, one file per app
+  loqui -export incident.jsonl.gz -format jsonl -split-labels app
+
+  # Build the selector interactively, then watch new lines arrive
+  loqui -exec -tail
+
+  # Pick a result and show the 20 lines around it in its stream
+  loqui -exec -context 20
+
+  # Find out which part of a query removes all results
+  loqui -diagnose
+
+  # Follow a request through all services during an incident
+  loqui trace -window 6h 4bf92f3577b34da6
+
+  # See which streams of a namespace exist and pick one as the selector
+  loqui series -pick 'namespace="checkout"'
+
+  # Find the labels with the most values over the last day
+  loqui stats labels -since 24h
+
+  # Check queries in CI
+  loqui lint -range 7d '{app=~".*"} |~ "error"'
 `
 
 type Config struct {
-	LogCLICmd string
-	TimeArgs  []string // Added to store time range arguments
-	Execute   bool     // Added for -exec option
+	LogCLICmd       string
+	LokiAddr        string
+	OrgID           string              // Tenant from LOKI_ORG_ID, part of the cache key
+	Cache           *LabelCache         // Cache for discovered labels and values
+	Selector        Selector            // Fuzzy finder used for labels and values
+	Usage           *UsageStats         // Label and value usage used for ranking
+	Favorites       []string            // Labels pinned to the top of the list
+	FavoriteValues  map[string][]string // Values pinned to the top, per label
+	HiddenLabels    []string            // Labels never offered
+	Prefetcher      *Prefetcher         // Background label value lookups for this session
+	PrefetchWorkers int                 // Maximum number of background lookups
+	LookupTimeout   time.Duration       // Timeout for a single logcli discovery call
+	TimeArgs        []string            // Added to store time range arguments
+	Execute         bool                // Added for -exec option
+	Format          string              // Output format for -exec mode
+	UTC             bool                // Show timestamps in UTC for -exec mode
+	Limit           int                 // Result limit, 0 for all, negative to ask
+	Direction       string              // backward or forward, empty to ask
+	BatchSize       int                 // logcli --batch, 0 to ask when needed
+	NoPager         bool                // Disable paging of -exec results
+	Tail            bool                // Follow new lines instead of a time range
+	Diagnose        bool                // Report how many lines each query stage leaves
+	Estimate        bool                // Estimate the scanned data before running
+	CostLimits      CostLimits          // Estimates above these ask for confirmation
+	DelayFor        int                 // Seconds logcli delays tailed lines
+	ContextLines    int                 // Lines shown around a picked result, 0 to not offer it
+	Export          ExportOptions
+
+	Output        string // Output mode selected with -output
+	ShowExplore   bool   // Print the Explore URL alongside the command
+	OpenBrowser   bool   // Open the Explore URL in the browser
+	GrafanaURL    string // Grafana base URL
+	DatasourceUID string // Grafana Loki datasource UID
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "lint":
+			os.Exit(runLint(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+		case "trace":
+			os.Exit(runTrace(os.Args[2:], os.Stdout, os.Stderr))
+		case "series":
+			os.Exit(runSeries(os.Args[2:], os.Stdout, os.Stderr))
+		case "stats":
+			os.Exit(runStats(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
+
 	var (
 		showHelp    bool
 		showVersion bool
+		configPath  string
+		selector    string
 		execute     bool
+		format      string
+		utc         bool
+		limit       int
+		direction   string
+		batchSize   int
+		noPager     bool
+		tail        bool
+		delayFor    int
+		contextN    int
+		diagnose    bool
+		noEstimate  bool
+		exportPath  string
+		chunkSize   time.Duration
+		parallel    int
+		splitTime   bool
+		splitLabels string
+		output      string
+		showExplore bool
+		openBrowser bool
+		grafanaURL  string
+		datasource  string
+		refresh     bool
+		cacheTTL    time.Duration
+		prefetch    int
+		timeout     time.Duration
 	)
 
 	flag.BoolVar(&showHelp, "help", false, "Show help")
 	flag.BoolVar(&showVersion, "version", false, "Show version")
+	flag.StringVar(&configPath, "config", "", "Configuration file")
+	flag.StringVar(&selector, "selector", "", "Fuzzy finder backend")
 	flag.BoolVar(&execute, "exec", false, "Execute the command immediately")
+	flag.StringVar(&format, "format", FormatDefault, "Output format for -exec")
+	flag.BoolVar(&utc, "utc", false, "Show timestamps in UTC")
+	flag.StringVar(&output, "output", OutputLogCLI, "What to print")
+	flag.BoolVar(&showExplore, "explore", false, "Also print a Grafana Explore URL")
+	flag.BoolVar(&openBrowser, "open", false, "Open the Grafana Explore URL in the browser")
+	flag.StringVar(&grafanaURL, "grafana-url", os.Getenv("GRAFANA_URL"), "Grafana base URL")
+	flag.StringVar(&datasource, "datasource", os.Getenv("GRAFANA_DATASOURCE_UID"), "Grafana Loki datasource UID")
+	flag.IntVar(&limit, "limit", -1, "Maximum number of lines, 0 for all")
+	flag.StringVar(&direction, "direction", "", "Result order: backward or forward")
+	flag.IntVar(&batchSize, "batch", 0, "Lines fetched per request")
+	flag.BoolVar(&noPager, "no-pager", false, "Do not page -exec results")
+	flag.BoolVar(&tail, "tail", false, "Follow new log lines")
+	flag.IntVar(&delayFor, "delay-for", 0, "Seconds to delay tailed lines")
+	flag.IntVar(&contextN, "context", 0, "Lines shown before and after a picked result")
+	flag.BoolVar(&diagnose, "diagnose", false, "Count the lines left by each query stage")
+	flag.BoolVar(&noEstimate, "no-estimate", false, "Do not estimate the query cost")
+	flag.BoolVar(&refresh, "refresh", false, "Ignore cached labels and values")
+	flag.DurationVar(&cacheTTL, "cache-ttl", defaultCacheTTL, "How long discovered labels and values are reused")
+	flag.DurationVar(&timeout, "timeout", defaultLookupTimeout, "Timeout for a single label lookup")
+	flag.IntVar(&prefetch, "prefetch", defaultPrefetchWorkers, "Number of label value lookups run in the background")
+	flag.StringVar(&exportPath, "export", "", "Write results to a file")
+	flag.DurationVar(&chunkSize, "chunk", time.Hour, "Time range fetched per logcli call when exporting")
+	flag.IntVar(&parallel, "parallel", 4, "Number of chunks fetched concurrently")
+	flag.BoolVar(&splitTime, "split-time", false, "Write one export file per chunk")
+	flag.StringVar(&splitLabels, "split-labels", "", "Write one export file per value of these labels")
 
 	flag.Usage = func() {
 		fmt.Fprint(os.Stderr, usage)
@@ -69,6 +272,85 @@ func main() {
 		os.Exit(0)
 	}
 
+	if err := validateFormat(format); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := validateOutput(output); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := validateExplore(output, showExplore, openBrowser, grafanaURL); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if direction != "" && direction != DirectionBackward && direction != DirectionForward {
+		fmt.Fprintf(os.Stderr, "Error: invalid direction: %s (expected backward or forward)\n", direction)
+		os.Exit(1)
+	}
+
+	if exportPath != "" && format == FormatColor {
+		fmt.Fprintf(os.Stderr, "Error: -format color cannot be exported\n")
+		os.Exit(1)
+	}
+	if exportPath != "" && tail {
+		fmt.Fprintf(os.Stderr, "Error: -export cannot be combined with -tail\n")
+		os.Exit(1)
+	}
+	if diagnose && exportPath != "" {
+		fmt.Fprintf(os.Stderr, "Error: -diagnose cannot be combined with -export\n")
+		os.Exit(1)
+	}
+	if diagnose && tail {
+		fmt.Fprintf(os.Stderr, "Error: -diagnose cannot be combined with -tail\n")
+		os.Exit(1)
+	}
+	if contextN < 0 {
+		fmt.Fprintf(os.Stderr, "Error: invalid context: %d\n", contextN)
+		os.Exit(1)
+	}
+	if contextN > 0 && (!execute || tail || exportPath != "" || diagnose) {
+		fmt.Fprintf(os.Stderr, "Error: -context requires -exec and cannot be combined with -tail, -export or -diagnose\n")
+		os.Exit(1)
+	}
+	if chunkSize <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: invalid chunk size: %s\n", chunkSize)
+		os.Exit(1)
+	}
+
+	fileConfig, err := loadFileConfig(configFile(configPath), configPath != "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// -selector wins over LOQUI_SELECTOR, which wins over the config file
+	if selector == "" {
+		selector = os.Getenv("LOQUI_SELECTOR")
+	}
+	if selector == "" {
+		selector = fileConfig.Selector
+	}
+	selectorBackend, err := newSelector(selector)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	costLimits, err := fileConfig.costLimits()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Ranking is a convenience, a broken stats file should not stop a query
+	usageStats, err := loadUsageStats(defaultUsagePath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring usage stats: %v\n", err)
+	}
+
 	// Check LOKI_ADDR environment variable
 	lokiAddr := os.Getenv("LOKI_ADDR")
 	if lokiAddr == "" {
@@ -79,14 +361,73 @@ func main() {
 	}
 
 	config := &Config{
-		LogCLICmd: "logcli",
-		TimeArgs:  []string{}, // Initialize as empty, will be set in InteractiveQueryBuilder
-		Execute:   execute,
+		LogCLICmd:       "logcli",
+		LokiAddr:        lokiAddr,
+		OrgID:           os.Getenv("LOKI_ORG_ID"),
+		Cache:           newLabelCache(defaultCacheDir(), cacheTTL, refresh),
+		Selector:        selectorBackend,
+		Usage:           usageStats,
+		Favorites:       fileConfig.Favorites,
+		FavoriteValues:  fileConfig.FavoriteValues,
+		HiddenLabels:    fileConfig.HiddenLabels,
+		PrefetchWorkers: prefetch,
+		LookupTimeout:   timeout,
+		TimeArgs:        []string{}, // Initialize as empty, will be set in InteractiveQueryBuilder
+		Execute:         execute,
+		Format:          format,
+		UTC:             utc,
+		Limit:           limit,
+		Direction:       direction,
+		BatchSize:       batchSize,
+		NoPager:         noPager,
+		Tail:            tail,
+		DelayFor:        delayFor,
+		ContextLines:    contextN,
+		Diagnose:        diagnose,
+		Estimate:        !noEstimate,
+		CostLimits:      costLimits,
+		Output:          output,
+		ShowExplore:     showExplore,
+		OpenBrowser:     openBrowser,
+		GrafanaURL:      grafanaURL,
+		DatasourceUID:   datasource,
+		Export: ExportOptions{
+			Path:        exportPath,
+			Format:      format,
+			UTC:         utc,
+			ChunkSize:   chunkSize,
+			Parallel:    parallel,
+			SplitTime:   splitTime,
+			SplitLabels: splitList(splitLabels),
+			BatchSize:   batchSize,
+		},
 	}
 
 	// Run interactive mode
 	if err := InteractiveQueryBuilder(config); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if hint := errorHint(err); hint != "" {
+			fmt.Fprintf(os.Stderr, "Hint: %s\n", hint)
+		}
 		os.Exit(1)
 	}
 }
+
+// splitList splits a comma separated flag value, dropping empty items
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// configFile returns the configuration file to read, preferring -config
+func configFile(path string) string {
+	if path != "" {
+		return path
+	}
+	return defaultConfigPath()
+}
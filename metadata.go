@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// structuredMetadataKeys returns the sorted names of the detected fields
+// stored as structured metadata rather than parsed from the line
+func structuredMetadataKeys(fields []DetectedField) []string {
+	keys := []string{}
+	for _, f := range fields {
+		if len(f.Parsers) == 0 {
+			keys = append(keys, f.Label)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sampleMetadataKeys returns the sorted labels of the samples that are not
+// stream labels. logcli reports structured metadata as labels of the entry.
+func sampleMetadataKeys(samples []LogEntry, streamLabels []string) []string {
+	indexed := map[string]bool{}
+	for _, label := range streamLabels {
+		indexed[label] = true
+	}
+
+	seen := map[string]bool{}
+	for _, e := range samples {
+		for label := range e.Labels {
+			if !indexed[label] {
+				seen[label] = true
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// discoverMetadataKeys finds the structured metadata keys of the lines query
+// matches, with the detected fields API or, when that is not available, by
+// comparing the labels of sample lines with the stream labels
+func discoverMetadataKeys(config *Config, query Query) ([]string, error) {
+	start, end, err := resolveTimeRange(config.TimeArgs, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	client := newLokiClient(config.LokiAddr, os.Getenv)
+	fields, err := lookup("Detecting structured metadata...", func(ctx context.Context) ([]DetectedField, error) {
+		ctx, cancel := context.WithTimeout(ctx, lookupTimeout(config))
+		defer cancel()
+		return client.DetectedFields(ctx, query.String(), start, end)
+	})
+	if err == nil {
+		if keys := structuredMetadataKeys(fields); len(keys) > 0 {
+			return keys, nil
+		}
+	}
+
+	streamLabels, err := lookup("Fetching labels...", func(ctx context.Context) ([]string, error) {
+		return getLabels(ctx, config)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get labels: %w", err)
+	}
+	samples, err := fetchSamples(config, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sample lines: %w", err)
+	}
+	return sampleMetadataKeys(samples, streamLabels), nil
+}
+
+// selectMetadataFilters asks for filters on structured metadata such as trace_id,
+// which Loki 3 does not index as stream labels
+func selectMetadataFilters(config *Config, query Query) ([]LabelSelector, error) {
+	fmt.Print("\nFilter by structured metadata, e.g. trace_id? (y/N): ")
+	answer, err := inputText("")
+	if err != nil {
+		return nil, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	if answer != "y" && answer != "yes" {
+		return nil, nil
+	}
+
+	keys, err := discoverMetadataKeys(config, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		fmt.Println("No structured metadata found in the matching lines.")
+		return nil, nil
+	}
+
+	filters := []LabelSelector{}
+	adding := true
+	for {
+		if adding {
+			key, err := selectItem(config, keys, "Select structured metadata key:")
+			if err != nil {
+				return nil, fmt.Errorf("key selection failed: %w", err)
+			}
+			filter, err := selectMetadataFilter(key)
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, filter)
+			adding = false
+		}
+
+		// Review the metadata filters before moving on
+		showCurrentMetadataFilters(filters)
+		action, index, err := promptReviewAction(len(filters), "metadata filter")
+		if err != nil {
+			return nil, err
+		}
+
+		switch action {
+		case ActionDone:
+			return filters, nil
+		case ActionAdd:
+			adding = true
+		case ActionEdit:
+			filter, err := selectMetadataFilter(filters[index].Label)
+			if err != nil {
+				return nil, err
+			}
+			filters[index] = filter
+		case ActionDelete:
+			filters = append(filters[:index], filters[index+1:]...)
+			if len(filters) == 0 {
+				return nil, nil
+			}
+		case ActionNegate:
+			filters[index] = filters[index].Negate()
+		}
+	}
+}
+
+func showCurrentMetadataFilters(filters []LabelSelector) {
+	fmt.Println("\n=== Current metadata filters ===")
+	for i, f := range filters {
+		fmt.Printf("%d. | %s\n", i+1, f)
+	}
+}
+
+// selectMetadataFilter asks for the operator and value of a filter on key
+// Values are typed in, metadata like trace IDs has too many to list
+func selectMetadataFilter(key string) (LabelSelector, error) {
+	operator, err := selectOperator(key)
+	if err != nil {
+		return LabelSelector{}, fmt.Errorf("operator selection failed: %w", err)
+	}
+
+	prompt := fmt.Sprintf("Enter value for '%s': ", key)
+	if operator == "=~" || operator == "!~" {
+		prompt = fmt.Sprintf("Enter regex pattern for '%s': ", key)
+	}
+	value, err := inputText(prompt)
+	if err != nil {
+		return LabelSelector{}, err
+	}
+
+	return LabelSelector{Label: key, Operator: operator, Value: value}, nil
+}
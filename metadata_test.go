@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStructuredMetadataKeys(t *testing.T) {
+	fields := []DetectedField{
+		{Label: "user_id"},
+		{Label: "status", Parsers: []string{"json"}},
+		{Label: "trace_id", Parsers: []string{}},
+	}
+	want := []string{"trace_id", "user_id"}
+	if got := structuredMetadataKeys(fields); !reflect.DeepEqual(got, want) {
+		t.Errorf("structuredMetadataKeys() = %v, want %v", got, want)
+	}
+}
+
+func TestSampleMetadataKeys(t *testing.T) {
+	samples := []LogEntry{
+		{Labels: map[string]string{"app": "nginx", "trace_id": "abc"}},
+		{Labels: map[string]string{"app": "nginx", "env": "prod", "user_id": "42"}},
+	}
+	want := []string{"trace_id", "user_id"}
+	if got := sampleMetadataKeys(samples, []string{"app", "env"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("sampleMetadataKeys() = %v, want %v", got, want)
+	}
+}
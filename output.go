@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Output modes selected with -output
+const (
+	OutputLogCLI  = "logcli"
+	OutputExplore = "explore"
+	OutputCurl    = "curl"
+	OutputURL     = "url"
+	OutputJSON    = "json"
+	OutputLogQL   = "logql"
+)
+
+var outputModes = []string{OutputLogCLI, OutputExplore, OutputCurl, OutputURL, OutputJSON, OutputLogQL}
+
+// queryRangePath is the Loki HTTP API endpoint for log queries
+const queryRangePath = "/loki/api/v1/query_range"
+
+// validateOutput checks that output is one of the supported output modes
+func validateOutput(output string) error {
+	for _, o := range outputModes {
+		if o == output {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid output: %s (expected one of %s)", output, strings.Join(outputModes, ", "))
+}
+
+// QuerySpec describes a built query for the json output mode
+type QuerySpec struct {
+	Query     string `json:"query"`
+	LokiAddr  string `json:"lokiAddr,omitempty"`
+	Since     string `json:"since,omitempty"`
+	From      string `json:"from,omitempty"`
+	To        string `json:"to,omitempty"`
+	Tail      bool   `json:"tail,omitempty"`
+	Limit     *int   `json:"limit,omitempty"`
+	Direction string `json:"direction,omitempty"`
+}
+
+// queryParam is a single query_range API parameter, kept in a fixed order
+type queryParam struct {
+	Key   string
+	Value string
+}
+
+// outputCommand prints the result in the selected output mode
+// The Explore link goes to stderr when shown alongside the command, so $(loqui) keeps working
+func outputCommand(config *Config, args []string, timeArgs []string, exploreURL string) error {
+	query := args[2]
+
+	switch config.Output {
+	case OutputExplore:
+		fmt.Println(exploreURL)
+		return nil
+	case OutputLogQL:
+		fmt.Println(query)
+		return nil
+	case OutputJSON:
+		data, err := json.MarshalIndent(buildQuerySpec(config, query, timeArgs), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	case OutputCurl, OutputURL:
+		if config.Tail {
+			return fmt.Errorf("output %s does not support tail mode", config.Output)
+		}
+		params, err := queryRangeParams(query, timeArgs, config.Limit, config.Direction)
+		if err != nil {
+			return err
+		}
+		if config.Output == OutputURL {
+			fmt.Println(buildQueryRangeURL(config.LokiAddr, params))
+		} else {
+			fmt.Println(buildCurlCommand(config.LokiAddr, params, os.Getenv))
+		}
+		return nil
+	}
+
+	fmt.Println(formatAsShellCommand(args))
+	if config.ShowExplore {
+		fmt.Fprintf(os.Stderr, "Grafana Explore: %s\n", exploreURL)
+	}
+	return nil
+}
+
+// buildQuerySpec describes the query, time range and result options
+func buildQuerySpec(config *Config, query string, timeArgs []string) QuerySpec {
+	spec := QuerySpec{
+		Query:    query,
+		LokiAddr: config.LokiAddr,
+		Tail:     config.Tail,
+	}
+
+	for i := 0; i+1 < len(timeArgs); i += 2 {
+		switch timeArgs[i] {
+		case "--since":
+			spec.Since = timeArgs[i+1]
+		case "--from":
+			spec.From = timeArgs[i+1]
+		case "--to":
+			spec.To = timeArgs[i+1]
+		}
+	}
+
+	if !config.Tail {
+		limit := config.Limit
+		spec.Limit = &limit
+		spec.Direction = config.Direction
+	}
+
+	return spec
+}
+
+// queryRangeParams converts the query and time range into query_range API parameters
+// Loki has no "all" limit over HTTP, so a limit of 0 leaves it to the server default
+func queryRangeParams(query string, timeArgs []string, limit int, direction string) ([]queryParam, error) {
+	params := []queryParam{{Key: "query", Value: query}}
+
+	if len(timeArgs) == 2 && timeArgs[0] == "--since" {
+		d, err := parseSince(timeArgs[1])
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, queryParam{Key: "since", Value: apiDuration(d)})
+	} else {
+		start, end, err := resolveTimeRange(timeArgs, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		params = append(params,
+			queryParam{Key: "start", Value: start.Format(time.RFC3339Nano)},
+			queryParam{Key: "end", Value: end.Format(time.RFC3339Nano)})
+	}
+
+	if limit > 0 {
+		params = append(params, queryParam{Key: "limit", Value: strconv.Itoa(limit)})
+	}
+	if direction != "" {
+		params = append(params, queryParam{Key: "direction", Value: direction})
+	}
+
+	return params, nil
+}
+
+// apiDuration formats d using only units understood by every Loki version
+func apiDuration(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return strconv.FormatInt(int64(d/time.Hour), 10) + "h"
+	case d%time.Minute == 0:
+		return strconv.FormatInt(int64(d/time.Minute), 10) + "m"
+	default:
+		return strconv.FormatInt(int64(d/time.Second), 10) + "s"
+	}
+}
+
+// buildQueryRangeURL returns the URL-encoded query_range API URL
+func buildQueryRangeURL(lokiAddr string, params []queryParam) string {
+	values := url.Values{}
+	for _, p := range params {
+		values.Set(p.Key, p.Value)
+	}
+	return strings.TrimRight(lokiAddr, "/") + queryRangePath + "?" + values.Encode()
+}
+
+// buildCurlCommand returns the curl invocation equivalent to the query
+// Credentials are referenced as environment variables rather than expanded
+func buildCurlCommand(lokiAddr string, params []queryParam, getenv func(string) string) string {
+	parts := []string{"curl", "-G", "-s", shellQuote(strings.TrimRight(lokiAddr, "/") + queryRangePath)}
+
+	if orgID := getenv("LOKI_ORG_ID"); orgID != "" {
+		parts = append(parts, "-H", shellQuote("X-Scope-OrgID: "+orgID))
+	}
+	if getenv("LOKI_BEARER_TOKEN") != "" {
+		parts = append(parts, "-H", `"Authorization: Bearer $LOKI_BEARER_TOKEN"`)
+	} else if getenv("LOKI_USERNAME") != "" {
+		parts = append(parts, "-u", `"$LOKI_USERNAME:$LOKI_PASSWORD"`)
+	}
+
+	for _, p := range params {
+		parts = append(parts, "--data-urlencode", shellQuote(p.Key+"="+p.Value))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// shellQuote quotes s for POSIX shells
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
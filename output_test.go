@@ -0,0 +1,123 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQueryRangeParams(t *testing.T) {
+	query := `{app="nginx"} |= "error"`
+
+	tests := []struct {
+		name      string
+		timeArgs  []string
+		limit     int
+		direction string
+		want      []queryParam
+	}{
+		{
+			name:      "relative range",
+			timeArgs:  []string{"--since", "7d"},
+			limit:     500,
+			direction: DirectionForward,
+			want: []queryParam{
+				{Key: "query", Value: query},
+				{Key: "since", Value: "168h"},
+				{Key: "limit", Value: "500"},
+				{Key: "direction", Value: "forward"},
+			},
+		},
+		{
+			name:      "absolute range without limit",
+			timeArgs:  []string{"--from", "2025-08-14T09:00:00+09:00", "--to", "2025-08-14T18:00:00+09:00"},
+			limit:     0,
+			direction: DirectionBackward,
+			want: []queryParam{
+				{Key: "query", Value: query},
+				{Key: "start", Value: "2025-08-14T09:00:00+09:00"},
+				{Key: "end", Value: "2025-08-14T18:00:00+09:00"},
+				{Key: "direction", Value: "backward"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := queryRangeParams(query, tt.timeArgs, tt.limit, tt.direction)
+			if err != nil {
+				t.Fatalf("queryRangeParams() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("queryRangeParams() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildQueryRangeURL(t *testing.T) {
+	params := []queryParam{
+		{Key: "query", Value: `{app="nginx"}`},
+		{Key: "since", Value: "1h"},
+	}
+	want := "http://localhost:3100/loki/api/v1/query_range?query=%7Bapp%3D%22nginx%22%7D&since=1h"
+
+	if got := buildQueryRangeURL("http://localhost:3100/", params); got != want {
+		t.Errorf("buildQueryRangeURL() = %q, want %q", got, want)
+	}
+}
+
+func TestBuildCurlCommand(t *testing.T) {
+	params := []queryParam{
+		{Key: "query", Value: `{app="nginx"} |= "it's"`},
+		{Key: "since", Value: "1h"},
+	}
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{
+			name: "no auth",
+			env:  map[string]string{},
+			want: `curl -G -s 'http://localhost:3100/loki/api/v1/query_range' --data-urlencode 'query={app="nginx"} |= "it'\''s"' --data-urlencode 'since=1h'`,
+		},
+		{
+			name: "tenant and basic auth",
+			env:  map[string]string{"LOKI_ORG_ID": "team-a", "LOKI_USERNAME": "user"},
+			want: `curl -G -s 'http://localhost:3100/loki/api/v1/query_range' -H 'X-Scope-OrgID: team-a' -u "$LOKI_USERNAME:$LOKI_PASSWORD" --data-urlencode 'query={app="nginx"} |= "it'\''s"' --data-urlencode 'since=1h'`,
+		},
+		{
+			name: "bearer token",
+			env:  map[string]string{"LOKI_BEARER_TOKEN": "secret"},
+			want: `curl -G -s 'http://localhost:3100/loki/api/v1/query_range' -H "Authorization: Bearer $LOKI_BEARER_TOKEN" --data-urlencode 'query={app="nginx"} |= "it'\''s"' --data-urlencode 'since=1h'`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string { return tt.env[key] }
+			if got := buildCurlCommand("http://localhost:3100", params, getenv); got != tt.want {
+				t.Errorf("buildCurlCommand() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildQuerySpec(t *testing.T) {
+	config := &Config{LokiAddr: "http://localhost:3100", Limit: 100, Direction: DirectionBackward}
+
+	got := buildQuerySpec(config, `{app="nginx"}`, []string{"--from", "2025-08-14T09:00:00+09:00", "--to", "2025-08-14T18:00:00+09:00"})
+	limit := 100
+	want := QuerySpec{
+		Query:     `{app="nginx"}`,
+		LokiAddr:  "http://localhost:3100",
+		From:      "2025-08-14T09:00:00+09:00",
+		To:        "2025-08-14T18:00:00+09:00",
+		Limit:     &limit,
+		Direction: DirectionBackward,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildQuerySpec() = %+v, want %+v", got, want)
+	}
+}
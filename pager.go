@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// defaultPager is used when the PAGER environment variable is not set
+const defaultPager = "less -R"
+
+// isTerminal reports whether f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Pager pipes output through the user's pager. logcli only fetches the next
+// batch once the previous one has been written, so results are fetched lazily
+// as the user scrolls and the pipe to the pager drains.
+type Pager struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+}
+
+// startPager starts $PAGER (or less) attached to the terminal
+func startPager() (*Pager, error) {
+	pagerCmd := os.Getenv("PAGER")
+	if pagerCmd == "" {
+		pagerCmd = defaultPager
+	}
+
+	cmd := exec.Command("sh", "-c", pagerCmd)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pager input: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start pager %q: %w", pagerCmd, err)
+	}
+
+	return &Pager{cmd: cmd, stdin: stdin}, nil
+}
+
+// Write sends output to the pager
+func (p *Pager) Write(b []byte) (int, error) {
+	return p.stdin.Write(b)
+}
+
+// Close signals the end of output and waits for the user to quit the pager
+func (p *Pager) Close() error {
+	_ = p.stdin.Close()
+	return p.cmd.Wait()
+}
+
+// isPagerClosed reports whether err was caused by the user quitting the pager
+// before all output was written
+func isPagerClosed(err error) bool {
+	return errors.Is(err, syscall.EPIPE) || errors.Is(err, os.ErrClosed)
+}
@@ -0,0 +1,525 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Parsers offered in the interactive flow
+const (
+	ParserJSON   = "json"
+	ParserLogfmt = "logfmt"
+)
+
+// sampleSize is the number of lines fetched to discover fields and preview stages
+const sampleSize = 20
+
+// previewSize is the number of sample lines shown in a preview
+const previewSize = 5
+
+// fetchSamples returns recent lines matching the selector and filters of query
+func fetchSamples(config *Config, query Query) ([]LogEntry, error) {
+	sampleQuery := Query{Selectors: query.Selectors, Metadata: query.Metadata, LineFilters: query.LineFilters}
+	args := buildLogCLIArgs(config.LogCLICmd, sampleQuery, append(append([]string{}, config.TimeArgs...), "--limit", strconv.Itoa(sampleSize)))
+
+	samples := []LogEntry{}
+	err := streamLogCLIQuery(args, func(e LogEntry) error {
+		samples = append(samples, e)
+		return nil
+	})
+	return samples, err
+}
+
+// extractFields returns the labels a parser extracts from a line,
+// named the way Loki names them
+func extractFields(parser, line string, streamLabels map[string]string) map[string]string {
+	raw := map[string]string{}
+	switch parser {
+	case ParserJSON:
+		var obj map[string]any
+		if err := json.Unmarshal([]byte(line), &obj); err == nil {
+			flattenJSON("", obj, raw)
+		}
+	case ParserLogfmt:
+		raw = parseLogfmt(line)
+	}
+
+	fields := make(map[string]string, len(raw))
+	for key, value := range raw {
+		name := sanitizeLabelName(key)
+		if _, ok := streamLabels[name]; ok {
+			// Loki keeps the stream label and renames the extracted one
+			name += "_extracted"
+		}
+		fields[name] = value
+	}
+	return fields
+}
+
+// flattenJSON joins nested object keys with _, like Loki's json parser
+// Arrays are skipped, other values are kept as their JSON text
+func flattenJSON(prefix string, obj map[string]any, fields map[string]string) {
+	for key, value := range obj {
+		name := key
+		if prefix != "" {
+			name = prefix + "_" + key
+		}
+
+		switch v := value.(type) {
+		case map[string]any:
+			flattenJSON(name, v, fields)
+		case []any:
+		case string:
+			fields[name] = v
+		case nil:
+			fields[name] = ""
+		default:
+			data, _ := json.Marshal(v)
+			fields[name] = string(data)
+		}
+	}
+}
+
+// parseLogfmt parses key=value pairs, with optionally quoted values
+func parseLogfmt(line string) map[string]string {
+	fields := map[string]string{}
+	for i := 0; i < len(line); {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' {
+			i++
+		}
+		key := line[start:i]
+		if i >= len(line) || line[i] != '=' {
+			// A bare key is a flag without value
+			if key != "" {
+				fields[key] = ""
+			}
+			continue
+		}
+		i++
+
+		value := ""
+		if i < len(line) && line[i] == '"' {
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			quoted := line[i:min(end+1, len(line))]
+			if unquoted, err := strconv.Unquote(quoted); err == nil {
+				value = unquoted
+			} else {
+				value = strings.Trim(quoted, `"`)
+			}
+			i = end + 1
+		} else {
+			start := i
+			for i < len(line) && line[i] != ' ' {
+				i++
+			}
+			value = line[start:i]
+		}
+
+		if key != "" {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// sanitizeLabelName replaces characters Loki does not allow in label names
+func sanitizeLabelName(name string) string {
+	var b strings.Builder
+	for i, c := range name {
+		switch {
+		case c == '_' || c < unicode.MaxASCII && unicode.IsLetter(c):
+			b.WriteRune(c)
+		case c < unicode.MaxASCII && unicode.IsDigit(c):
+			if i == 0 {
+				b.WriteRune('_')
+			}
+			b.WriteRune(c)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// sampleLabels returns the stream and extracted labels of each sample
+func sampleLabels(parser string, samples []LogEntry) []map[string]string {
+	labels := make([]map[string]string, len(samples))
+	for i, e := range samples {
+		all := make(map[string]string, len(e.Labels))
+		for k, v := range e.Labels {
+			all[k] = v
+		}
+		for k, v := range extractFields(parser, e.Line, e.Labels) {
+			all[k] = v
+		}
+		labels[i] = all
+	}
+	return labels
+}
+
+// fieldNames returns the sorted extracted field names found in the samples
+func fieldNames(parser string, samples []LogEntry) []string {
+	seen := map[string]bool{}
+	for _, e := range samples {
+		for name := range extractFields(parser, e.Line, e.Labels) {
+			seen[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// selectPipeline asks for a parser and the formatting stages following it
+func selectPipeline(config *Config, query *Query) error {
+	parser, err := selectParser()
+	if err != nil {
+		return err
+	}
+	if parser == "" {
+		return nil
+	}
+	query.Parser = parser
+
+	samples, err := fetchSamples(config, *query)
+	if err != nil {
+		return fmt.Errorf("failed to fetch sample lines: %w", err)
+	}
+	if len(samples) == 0 {
+		fmt.Println("No sample lines found, fields cannot be offered or previewed.")
+	}
+
+	fields := fieldNames(parser, samples)
+	if len(fields) > 0 {
+		fmt.Printf("\nFields extracted by %s: %s\n", parser, strings.Join(fields, ", "))
+	}
+	labels := sampleLabels(parser, samples)
+
+	if err := selectLabelFormat(query, samples, labels); err != nil {
+		return err
+	}
+	// line_format sees the labels as label_format left them
+	for i, e := range samples {
+		formatted, err := applyLabelFormats(query.LabelFormats, labels[i], e.Line, e.Timestamp)
+		if err == nil {
+			labels[i] = formatted
+		}
+	}
+	if err := selectLineFormat(query, samples, labels); err != nil {
+		return err
+	}
+	return selectDropKeep(config, query, labels)
+}
+
+func selectParser() (string, error) {
+	fmt.Println("\nParse log lines into labels (default: 1):")
+	fmt.Println("1. none")
+	fmt.Println("2. json")
+	fmt.Println("3. logfmt")
+	fmt.Print("Enter number (1-3) or press Enter for default: ")
+
+	choice, err := inputText("")
+	if err != nil {
+		return "", err
+	}
+
+	switch choice {
+	case "", "1":
+		return "", nil
+	case "2":
+		return ParserJSON, nil
+	case "3":
+		return ParserLogfmt, nil
+	default:
+		return "", fmt.Errorf("invalid choice: %s", choice)
+	}
+}
+
+// selectLabelFormat asks for label_format assignments, previewing them until accepted
+func selectLabelFormat(query *Query, samples []LogEntry, labels []map[string]string) error {
+	for {
+		fmt.Print("\nRename or template labels with label_format, e.g. svc=app, summary=\"{{.level}}: {{.msg}}\" (Enter to skip): ")
+		input, err := inputText("")
+		if err != nil {
+			return err
+		}
+		if input == "" {
+			return nil
+		}
+
+		formats, err := parseLabelFormats(input)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		fmt.Println("\nPreview:")
+		for i, e := range samples[:min(previewSize, len(samples))] {
+			formatted, err := applyLabelFormats(formats, labels[i], e.Line, e.Timestamp)
+			if err != nil {
+				fmt.Printf("  error: %v\n", err)
+				continue
+			}
+			changed := map[string]string{}
+			for _, f := range formats {
+				changed[f.Dst] = formatted[f.Dst]
+			}
+			fmt.Printf("  %s\n", formatLabels(changed))
+		}
+
+		ok, err := confirmStage()
+		if err != nil {
+			return err
+		}
+		if ok {
+			query.LabelFormats = formats
+			return nil
+		}
+	}
+}
+
+// selectLineFormat asks for a line_format template, previewing it until accepted
+func selectLineFormat(query *Query, samples []LogEntry, labels []map[string]string) error {
+	for {
+		fmt.Print("\nReshape lines with line_format, e.g. {{.level}} {{.msg}} (Enter to skip): ")
+		text, err := inputText("")
+		if err != nil {
+			return err
+		}
+		if text == "" {
+			return nil
+		}
+
+		if err := validateTemplate(text); err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		fmt.Println("\nPreview:")
+		for i, e := range samples[:min(previewSize, len(samples))] {
+			line, err := renderTemplate(text, labels[i], e.Line, e.Timestamp)
+			if err != nil {
+				line = "error: " + err.Error()
+			}
+			fmt.Printf("  %s\n", line)
+		}
+
+		ok, err := confirmStage()
+		if err != nil {
+			return err
+		}
+		if ok {
+			query.LineFormat = text
+			return nil
+		}
+	}
+}
+
+// confirmStage asks whether to add a previewed stage, "n" enters it again
+func confirmStage() (bool, error) {
+	fmt.Print("Add this stage? (Y/n to change it): ")
+	answer, err := inputText("")
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer != "n" && answer != "no", nil
+}
+
+// selectDropKeep asks for a drop or keep stage over the labels of the samples
+func selectDropKeep(config *Config, query *Query, labels []map[string]string) error {
+	fmt.Println("\nRemove labels from the results (default: 1):")
+	fmt.Println("1. none")
+	fmt.Println("2. drop the selected labels")
+	fmt.Println("3. keep only the selected labels")
+	fmt.Print("Enter number (1-3) or press Enter for default: ")
+
+	choice, err := inputText("")
+	if err != nil {
+		return err
+	}
+	keep := false
+	switch choice {
+	case "", "1":
+		return nil
+	case "2":
+	case "3":
+		keep = true
+	default:
+		return fmt.Errorf("invalid choice: %s", choice)
+	}
+
+	names := labelNames(labels)
+	stage := "drop"
+	if keep {
+		stage = "keep"
+	}
+
+	selected := []LabelSelector{}
+	if len(names) > 0 {
+		picked, err := config.Selector.SelectMulti(names, fmt.Sprintf("Labels to %s:", stage))
+		if err != nil {
+			return fmt.Errorf("label selection failed: %w", err)
+		}
+		for _, name := range picked {
+			selected = append(selected, LabelSelector{Label: name})
+		}
+	}
+
+	var conditions []LabelSelector
+	for {
+		fmt.Printf("Only %s when the value matches, e.g. level=\"debug\" (comma separated, Enter for always): ", stage)
+		input, err := inputText("")
+		if err != nil {
+			return err
+		}
+
+		conditions, err = parseConditions(input)
+		if err == nil {
+			break
+		}
+		fmt.Println(err)
+	}
+	selected = mergeConditions(selected, conditions)
+
+	if len(selected) == 0 {
+		return nil
+	}
+	if keep {
+		query.Keep = selected
+	} else {
+		query.Drop = selected
+	}
+
+	fmt.Println("\nPreview:")
+	for _, l := range labels[:min(previewSize, len(labels))] {
+		fmt.Printf("  %s\n", formatLabels(applyDropKeep(l, query.Drop, query.Keep)))
+	}
+	return nil
+}
+
+// labelNames returns the sorted names of all labels in the samples
+func labelNames(labels []map[string]string) []string {
+	seen := map[string]bool{}
+	for _, l := range labels {
+		for name := range l {
+			seen[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseConditions parses comma separated matchers like level="debug", method=~"GET|HEAD"
+func parseConditions(input string) ([]LabelSelector, error) {
+	tokens, err := tokenizeLogQL(input)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := []LabelSelector{}
+	for i := 0; i < len(tokens); {
+		if i+2 >= len(tokens) || tokens[i].Quoted || !tokens[i+2].Quoted || !labelNamePattern.MatchString(tokens[i].Text) {
+			return nil, fmt.Errorf("invalid condition, expected label=\"value\"")
+		}
+		op := tokens[i+1].Text
+		if _, ok := negatedOperators[op]; !ok {
+			return nil, fmt.Errorf("invalid condition operator: %s", op)
+		}
+		conditions = append(conditions, LabelSelector{Label: tokens[i].Text, Operator: op, Value: tokens[i+2].Text})
+
+		i += 3
+		if i < len(tokens) {
+			if tokens[i].Text != "," || tokens[i].Quoted {
+				return nil, fmt.Errorf("invalid condition, separate conditions with commas")
+			}
+			i++
+		}
+	}
+	return conditions, nil
+}
+
+// mergeConditions replaces the plain entries of labels that got a condition
+// and adds the conditions on other labels
+func mergeConditions(selected, conditions []LabelSelector) []LabelSelector {
+	conditional := map[string]bool{}
+	for _, c := range conditions {
+		conditional[c.Label] = true
+	}
+
+	merged := []LabelSelector{}
+	for _, s := range selected {
+		if !conditional[s.Label] {
+			merged = append(merged, s)
+		}
+	}
+	return append(merged, conditions...)
+}
+
+// matchesCondition reports whether value satisfies a drop or keep entry
+func matchesCondition(c LabelSelector, value string) bool {
+	switch c.Operator {
+	case "":
+		return true
+	case "=":
+		return value == c.Value
+	case "!=":
+		return value != c.Value
+	}
+
+	// Label matchers are anchored regexes
+	re, err := regexp.Compile("^(?:" + c.Value + ")$")
+	if err != nil {
+		return false
+	}
+	return re.MatchString(value) == (c.Operator == "=~")
+}
+
+// applyDropKeep returns the labels left by the drop and keep stages
+func applyDropKeep(labels map[string]string, drop, keep []LabelSelector) map[string]string {
+	result := map[string]string{}
+	for name, value := range labels {
+		dropped := false
+		for _, d := range drop {
+			if d.Label == name && matchesCondition(d, value) {
+				dropped = true
+			}
+		}
+
+		kept := len(keep) == 0
+		for _, k := range keep {
+			if k.Label == name && matchesCondition(k, value) {
+				kept = true
+			}
+		}
+
+		if kept && !dropped {
+			result[name] = value
+		}
+	}
+	return result
+}
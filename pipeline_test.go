@@ -0,0 +1,207 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		parser string
+		line   string
+		stream map[string]string
+		want   map[string]string
+	}{
+		{
+			name:   "json flattens nested objects",
+			parser: ParserJSON,
+			line:   `{"level":"error","status":500,"ok":false,"req":{"method":"GET","path":"/"},"tags":["a"],"user-id":"42"}`,
+			want: map[string]string{
+				"level": "error", "status": "500", "ok": "false",
+				"req_method": "GET", "req_path": "/", "user_id": "42",
+			},
+		},
+		{
+			name:   "json field clashing with a stream label",
+			parser: ParserJSON,
+			line:   `{"app":"api","msg":"hi"}`,
+			stream: map[string]string{"app": "nginx"},
+			want:   map[string]string{"app_extracted": "api", "msg": "hi"},
+		},
+		{
+			name:   "json parse failure",
+			parser: ParserJSON,
+			line:   `plain text`,
+			want:   map[string]string{},
+		},
+		{
+			name:   "logfmt",
+			parser: ParserLogfmt,
+			line:   `level=info msg="request done" duration=12ms cached 1st=x`,
+			want: map[string]string{
+				"level": "info", "msg": "request done", "duration": "12ms", "cached": "", "_1st": "x",
+			},
+		},
+		{
+			name:   "logfmt escaped quote",
+			parser: ParserLogfmt,
+			line:   `msg="say \"hi\"" level=debug`,
+			want:   map[string]string{"msg": `say "hi"`, "level": "debug"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := extractFields(tt.parser, tt.line, tt.stream)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldNames(t *testing.T) {
+	samples := []LogEntry{
+		{Line: `level=info msg=a`},
+		{Line: `level=error err=timeout`},
+	}
+	got := fieldNames(ParserLogfmt, samples)
+	want := []string{"err", "level", "msg"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fieldNames() = %v, want %v", got, want)
+	}
+}
+
+func TestFetchSamples(t *testing.T) {
+	logcli, calls := fakeLogCLI(t, `echo '{"timestamp":"2025-08-14T01:00:00Z","labels":{"app":"nginx"},"line":"level=info"}'`)
+	config := &Config{LogCLICmd: logcli, TimeArgs: []string{"--since", "1h"}}
+	query := Query{
+		Selectors: []LabelSelector{{Label: "app", Operator: "=", Value: "nginx"}},
+		Parser:    ParserLogfmt,
+	}
+
+	samples, err := fetchSamples(config, query)
+	if err != nil {
+		t.Fatalf("fetchSamples() error = %v", err)
+	}
+	if len(samples) != 1 || samples[0].Line != "level=info" {
+		t.Errorf("fetchSamples() = %+v", samples)
+	}
+
+	want := `query {app="nginx"} --since 1h --limit 20 --output=jsonl --quiet`
+	if got := readCalls(t, calls); len(got) != 1 || got[0] != want {
+		t.Errorf("logcli called with %v, want %s", got, want)
+	}
+}
+
+func TestParseConditions(t *testing.T) {
+	got, err := parseConditions(`level="debug", method=~"GET|HEAD"`)
+	if err != nil {
+		t.Fatalf("parseConditions() error = %v", err)
+	}
+	want := []LabelSelector{
+		{Label: "level", Operator: "=", Value: "debug"},
+		{Label: "method", Operator: "=~", Value: "GET|HEAD"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseConditions() = %v, want %v", got, want)
+	}
+
+	if got, err := parseConditions(""); err != nil || len(got) != 0 {
+		t.Errorf("parseConditions(\"\") = %v, %v", got, err)
+	}
+	for _, input := range []string{"level", `level=debug`, `level="debug" method="GET"`, `level|="debug"`} {
+		if _, err := parseConditions(input); err == nil {
+			t.Errorf("parseConditions(%q) expected error", input)
+		}
+	}
+}
+
+func TestConditionsRoundTrip(t *testing.T) {
+	// The tokenizer unquotes values, rendering must quote them again
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`msg=~"\\d+"`, "{app=\"nginx\"} | drop msg=~`\\d+`"},
+		{"msg=~`\\d+`", "{app=\"nginx\"} | drop msg=~`\\d+`"},
+		{`path="a\"b", level="debug"`, "{app=\"nginx\"} | drop path=`a\"b`, level=\"debug\""},
+	}
+
+	for _, tt := range tests {
+		conditions, err := parseConditions(tt.input)
+		if err != nil {
+			t.Fatalf("parseConditions(%q) error = %v", tt.input, err)
+		}
+		query := Query{Selectors: []LabelSelector{{Label: "app", Operator: "=", Value: "nginx"}}, Drop: conditions}
+		if got := query.String(); got != tt.want {
+			t.Errorf("Query.String() = %s, want %s", got, tt.want)
+		}
+
+		// Rendered conditions parse back to the same values
+		rendered := strings.TrimPrefix(query.String(), `{app="nginx"} | drop `)
+		again, err := parseConditions(rendered)
+		if err != nil {
+			t.Fatalf("parseConditions(%q) error = %v", rendered, err)
+		}
+		if !reflect.DeepEqual(again, conditions) {
+			t.Errorf("parseConditions(%q) = %v, want %v", rendered, again, conditions)
+		}
+	}
+}
+
+func TestMergeConditions(t *testing.T) {
+	selected := []LabelSelector{{Label: "level"}, {Label: "req_id"}}
+	conditions := []LabelSelector{
+		{Label: "level", Operator: "=", Value: "debug"},
+		{Label: "method", Operator: "=", Value: "GET"},
+	}
+
+	got := mergeConditions(selected, conditions)
+	want := []LabelSelector{
+		{Label: "req_id"},
+		{Label: "level", Operator: "=", Value: "debug"},
+		{Label: "method", Operator: "=", Value: "GET"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeConditions() = %v, want %v", got, want)
+	}
+}
+
+func TestApplyDropKeep(t *testing.T) {
+	labels := map[string]string{"app": "nginx", "level": "debug", "method": "GET", "req_id": "42"}
+
+	tests := []struct {
+		name string
+		drop []LabelSelector
+		keep []LabelSelector
+		want map[string]string
+	}{
+		{
+			name: "drop",
+			drop: []LabelSelector{{Label: "req_id"}, {Label: "method", Operator: "=~", Value: "POST|PUT"}},
+			want: map[string]string{"app": "nginx", "level": "debug", "method": "GET"},
+		},
+		{
+			name: "conditional drop",
+			drop: []LabelSelector{{Label: "level", Operator: "=", Value: "debug"}, {Label: "method", Operator: "!=", Value: "GET"}},
+			want: map[string]string{"app": "nginx", "method": "GET", "req_id": "42"},
+		},
+		{
+			name: "keep",
+			keep: []LabelSelector{{Label: "app"}, {Label: "level", Operator: "=~", Value: "info|warn"}},
+			want: map[string]string{"app": "nginx"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := applyDropKeep(labels, tt.drop, tt.keep)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applyDropKeep() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
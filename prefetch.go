@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultPrefetchWorkers bounds the number of background label value lookups
+const defaultPrefetchWorkers = 4
+
+// commonLabels are warmed in the background when present,
+// since most queries start with one of them
+var commonLabels = []string{"app", "namespace", "env", "job", "service_name", "container", "cluster", "level"}
+
+// Prefetcher fetches label values concurrently with the interactive prompts.
+// Each label is fetched at most once per session; callers asking for a label
+// that is still being fetched wait for that lookup instead of starting another.
+// A nil Prefetcher fetches synchronously.
+type Prefetcher struct {
+	config *Config
+	ctx    context.Context
+	cancel context.CancelFunc
+	sem    chan struct{}
+
+	mu      sync.Mutex
+	fetches map[string]*valuesFetch
+}
+
+// valuesFetch is a label value lookup that is running or has finished
+type valuesFetch struct {
+	done   chan struct{}
+	values []string
+	err    error
+}
+
+// newPrefetcher creates a prefetcher running at most workers background lookups
+// Warming is disabled when workers is zero, but picked labels are still prefetched
+func newPrefetcher(config *Config, workers int) *Prefetcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Prefetcher{
+		config:  config,
+		ctx:     ctx,
+		cancel:  cancel,
+		sem:     make(chan struct{}, max(workers, 0)),
+		fetches: map[string]*valuesFetch{},
+	}
+}
+
+// Close cancels all lookups that are still running
+func (p *Prefetcher) Close() {
+	if p != nil {
+		p.cancel()
+	}
+}
+
+// Prefetch starts fetching the values of a label the user just picked
+func (p *Prefetcher) Prefetch(label string) {
+	if p == nil {
+		return
+	}
+	p.start(label, false)
+}
+
+// Warm fetches values of the favorite, most used and common labels found in
+// labels in the background, with at most the configured number of lookups
+// running at once
+func (p *Prefetcher) Warm(labels []string) {
+	if p == nil || cap(p.sem) == 0 {
+		return
+	}
+
+	available := make(map[string]bool)
+	for _, label := range labels {
+		available[label] = true
+	}
+
+	for _, label := range warmCandidates(p.config) {
+		if available[label] {
+			p.start(label, true)
+		}
+	}
+}
+
+// warmCandidates returns the labels worth warming, most likely to be picked first
+func warmCandidates(config *Config) []string {
+	candidates := append([]string{}, config.Favorites...)
+	candidates = append(candidates, config.Usage.TopLabels(len(commonLabels))...)
+	candidates = append(candidates, commonLabels...)
+
+	seen := make(map[string]bool)
+	unique := candidates[:0]
+	for _, label := range candidates {
+		if !seen[label] {
+			seen[label] = true
+			unique = append(unique, label)
+		}
+	}
+	return unique
+}
+
+// Values returns the values of label, waiting for a running lookup if there is one
+// Cancelling ctx stops the wait, while the lookup itself keeps running for later use
+func (p *Prefetcher) Values(ctx context.Context, config *Config, label string) ([]string, error) {
+	if p == nil {
+		return getLabelValues(ctx, config, label)
+	}
+
+	f := p.start(label, false)
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if f.err != nil {
+		// Do not keep failures around, the next attempt fetches again
+		p.mu.Lock()
+		if p.fetches[label] == f {
+			delete(p.fetches, label)
+		}
+		p.mu.Unlock()
+	}
+	return f.values, f.err
+}
+
+// start returns the lookup for label, starting it if needed
+// Bounded lookups wait for a free worker slot, others run immediately
+func (p *Prefetcher) start(label string, bounded bool) *valuesFetch {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if f, ok := p.fetches[label]; ok {
+		return f
+	}
+
+	f := &valuesFetch{done: make(chan struct{})}
+	p.fetches[label] = f
+
+	go func() {
+		defer close(f.done)
+
+		if bounded {
+			select {
+			case p.sem <- struct{}{}:
+				defer func() { <-p.sem }()
+			case <-p.ctx.Done():
+				f.err = p.ctx.Err()
+				return
+			}
+		}
+
+		f.values, f.err = getLabelValues(p.ctx, p.config, label)
+	}()
+
+	return f
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeLogCLI writes a logcli stand-in that prints script output and records its arguments
+func fakeLogCLI(t *testing.T, script string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+	path := filepath.Join(dir, "logcli")
+	content := "#!/bin/sh\necho \"$@\" >> " + calls + "\n" + script + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path, calls
+}
+
+func readCalls(t *testing.T, calls string) []string {
+	t.Helper()
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		return nil
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestPrefetcherFetchesEachLabelOnce(t *testing.T) {
+	logcli, calls := fakeLogCLI(t, "sleep 0.1; printf 'nginx\\napi\\n'")
+	config := &Config{LogCLICmd: logcli, TimeArgs: []string{"--since", "1h"}}
+
+	p := newPrefetcher(config, 2)
+	defer p.Close()
+
+	p.Prefetch("app")
+	for i := 0; i < 3; i++ {
+		values, err := p.Values(context.Background(), config, "app")
+		if err != nil {
+			t.Fatalf("Values() error = %v", err)
+		}
+		if want := []string{"nginx", "api"}; !reflect.DeepEqual(values, want) {
+			t.Errorf("Values() = %v, want %v", values, want)
+		}
+	}
+
+	if got := readCalls(t, calls); len(got) != 1 {
+		t.Errorf("logcli called %d times, want 1: %v", len(got), got)
+	}
+}
+
+func TestPrefetcherWarmsCommonLabels(t *testing.T) {
+	logcli, calls := fakeLogCLI(t, "echo value")
+	config := &Config{LogCLICmd: logcli, TimeArgs: []string{"--since", "1h"}}
+
+	p := newPrefetcher(config, 1)
+	defer p.Close()
+
+	p.Warm([]string{"app", "filename", "namespace"})
+	for _, label := range []string{"app", "namespace"} {
+		if _, err := p.Values(context.Background(), config, label); err != nil {
+			t.Fatalf("Values(%s) error = %v", label, err)
+		}
+	}
+
+	got := readCalls(t, calls)
+	if len(got) != 2 {
+		t.Fatalf("logcli called %d times, want 2: %v", len(got), got)
+	}
+	for _, call := range got {
+		if strings.Contains(call, "filename") {
+			t.Errorf("uncommon label was warmed: %s", call)
+		}
+	}
+}
+
+func TestPrefetcherCloseCancelsLookups(t *testing.T) {
+	logcli, _ := fakeLogCLI(t, "exec sleep 10")
+	config := &Config{LogCLICmd: logcli, TimeArgs: []string{"--since", "1h"}}
+
+	p := newPrefetcher(config, 1)
+	p.Prefetch("app")
+	p.Close()
+
+	if _, err := p.Values(context.Background(), config, "app"); err == nil {
+		t.Error("Values() succeeded after Close()")
+	}
+}
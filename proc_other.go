@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "os/exec"
+
+// isolateFromTerminal is a no-op where process groups are not available
+func isolateFromTerminal(cmd *exec.Cmd) {}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// isolateFromTerminal starts cmd in its own process group, so Ctrl-C in the
+// terminal does not reach it; loqui cancels it through its context instead
+func isolateFromTerminal(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Query is the LogQL query being built: a stream selector followed by
+// structured metadata and line filters, an optional parser and formatting stages
+type Query struct {
+	Selectors    []LabelSelector
+	Metadata     []LabelSelector // Structured metadata filters, | trace_id="..."
+	LineFilters  []LineFilter
+	Parser       string          // json or logfmt, empty for none
+	LabelFormats []LabelFormat   // Renamed and templated labels
+	LineFormat   string          // Template replacing the log line, empty for none
+	Drop         []LabelSelector // Labels removed, with an optional value condition
+	Keep         []LabelSelector // Labels kept, with an optional value condition
+}
+
+// String renders the query as LogQL
+func (q Query) String() string {
+	query := "{"
+	for i, s := range q.Selectors {
+		if i > 0 {
+			query += ","
+		}
+		query += s.String()
+	}
+	query += "}"
+
+	for _, m := range q.Metadata {
+		query += " | " + m.String()
+	}
+	for _, f := range q.LineFilters {
+		query += " " + f.String()
+	}
+
+	if q.Parser != "" {
+		query += " | " + q.Parser
+	}
+	if len(q.LabelFormats) > 0 {
+		formats := make([]string, len(q.LabelFormats))
+		for i, f := range q.LabelFormats {
+			formats[i] = f.String()
+		}
+		query += " | label_format " + strings.Join(formats, ", ")
+	}
+	if q.LineFormat != "" {
+		query += " | line_format " + logqlString(q.LineFormat)
+	}
+	// drop and keep come last, so line_format can still use the labels they remove
+	if len(q.Drop) > 0 {
+		query += " | drop " + labelList(q.Drop)
+	}
+	if len(q.Keep) > 0 {
+		query += " | keep " + labelList(q.Keep)
+	}
+
+	return query
+}
+
+// labelList renders the labels of a drop or keep stage, e.g. level, method="GET"
+func labelList(labels []LabelSelector) string {
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.Label
+		if l.Operator != "" {
+			parts[i] = l.String()
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// logqlString quotes s as a LogQL string, using backticks when that avoids escaping
+func logqlString(s string) string {
+	if strings.ContainsAny(s, "\"\\") && !strings.Contains(s, "`") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+// String renders the matcher, quoting the value like line filter text so
+// one query never mixes two quoting rules
+func (s LabelSelector) String() string {
+	return s.Label + s.Operator + logqlString(s.Value)
+}
+
+func (f LineFilter) String() string {
+	if f.IP {
+		return fmt.Sprintf("%s ip(%s)", f.Operator, strconv.Quote(f.Text))
+	}
+	filter := f.Operator + " " + logqlString(f.Text)
+	for _, term := range f.Or {
+		filter += " or " + logqlString(term)
+	}
+	return filter
+}
+
+// caseInsensitiveFilter matches text in any case, e.g. |~ `(?i)time\.out`
+func caseInsensitiveFilter(text string) LineFilter {
+	return LineFilter{Operator: "|~", Text: "(?i)" + regexp.QuoteMeta(text)}
+}
+
+// anyOfFilter matches lines containing any of the comma separated terms
+func anyOfFilter(input string) (LineFilter, error) {
+	terms := []string{}
+	for _, term := range strings.Split(input, ",") {
+		if term = strings.TrimSpace(term); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	if len(terms) == 0 {
+		return LineFilter{}, fmt.Errorf("no terms given")
+	}
+	return LineFilter{Operator: "|=", Text: terms[0], Or: terms[1:]}, nil
+}
+
+// ipFilter matches lines containing an address in a range or CIDR, as Loki's
+// ip() accepts: 192.168.0.1, 192.168.0.1-192.168.0.9 or 10.0.0.0/8
+func ipFilter(address string) (LineFilter, error) {
+	address = strings.TrimSpace(address)
+	valid := false
+	switch {
+	case strings.Contains(address, "/"):
+		_, _, err := net.ParseCIDR(address)
+		valid = err == nil
+	case strings.Contains(address, "-"):
+		from, to, _ := strings.Cut(address, "-")
+		valid = net.ParseIP(strings.TrimSpace(from)) != nil && net.ParseIP(strings.TrimSpace(to)) != nil
+	default:
+		valid = net.ParseIP(address) != nil
+	}
+	if !valid {
+		return LineFilter{}, fmt.Errorf("invalid IP address, range or CIDR: %s", address)
+	}
+	return LineFilter{Operator: "|=", Text: address, IP: true}, nil
+}
+
+// negatedOperators flips a matcher between its positive and negative form
+var negatedOperators = map[string]string{
+	"=":  "!=",
+	"!=": "=",
+	"=~": "!~",
+	"!~": "=~",
+}
+
+// negatedLineOperators flips a line filter between its positive and negative form
+var negatedLineOperators = map[string]string{
+	"|=": "!=",
+	"!=": "|=",
+	"|~": "!~",
+	"!~": "|~",
+}
+
+// Negate returns the selector with the opposite operator
+func (s LabelSelector) Negate() LabelSelector {
+	s.Operator = negatedOperators[s.Operator]
+	return s
+}
+
+// Negate returns the line filter with the opposite operator
+func (f LineFilter) Negate() LineFilter {
+	f.Operator = negatedLineOperators[f.Operator]
+	return f
+}
+
+// Actions offered when reviewing the selectors or line filters of a query
+const (
+	ActionDone   = "done"
+	ActionAdd    = "add"
+	ActionEdit   = "edit"
+	ActionDelete = "delete"
+	ActionNegate = "negate"
+)
+
+// parseReviewAction parses an answer to the review prompt, e.g. "a", "e 2" or "n1"
+// count is the number of items an edit, delete or negate can refer to
+func parseReviewAction(answer string, count int) (string, int, error) {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	// A bare "n" answered the former "Add another? (y/N)" prompt, so it
+	// still means no, never negate
+	if answer == "" || answer == "n" || answer == "no" {
+		return ActionDone, 0, nil
+	}
+
+	actions := map[string]string{
+		"a": ActionAdd, "y": ActionAdd, "yes": ActionAdd,
+		"e": ActionEdit, "d": ActionDelete, "n": ActionNegate,
+	}
+	if action, ok := actions[answer]; ok && action == ActionAdd {
+		return action, 0, nil
+	}
+
+	action, ok := actions[answer[:1]]
+	if !ok || action == ActionAdd {
+		return "", 0, fmt.Errorf("invalid choice: %s", answer)
+	}
+
+	arg := strings.TrimSpace(answer[1:])
+	if arg == "" && count == 1 && action == ActionEdit {
+		// Nothing to choose from; delete and negate change the query
+		// silently, so they always need the number
+		return action, 0, nil
+	}
+	num, err := strconv.Atoi(arg)
+	if err != nil || num < 1 || num > count {
+		return "", 0, fmt.Errorf("invalid number: %s (expected 1-%d)", arg, count)
+	}
+	return action, num - 1, nil
+}
+
+// promptReviewAction asks what to do with the count listed items next
+func promptReviewAction(count int, noun string) (string, int, error) {
+	for {
+		fmt.Printf("\nAdd %s (a), edit (e N), delete (d N), negate (n N), or press Enter to continue: ", noun)
+		answer, err := inputText("")
+		if err != nil {
+			return "", 0, err
+		}
+
+		action, index, err := parseReviewAction(answer, count)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		return action, index, nil
+	}
+}
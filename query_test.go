@@ -0,0 +1,187 @@
+package main
+
+import "testing"
+
+func TestNegate(t *testing.T) {
+	selectors := map[string]string{"=": "!=", "!=": "=", "=~": "!~", "!~": "=~"}
+	for op, want := range selectors {
+		s := LabelSelector{Label: "app", Operator: op, Value: "nginx"}
+		if got := s.Negate(); got.Operator != want || got.Label != "app" || got.Value != "nginx" {
+			t.Errorf("LabelSelector{%s}.Negate() = %+v, want operator %s", op, got, want)
+		}
+	}
+
+	filters := map[string]string{"|=": "!=", "!=": "|=", "|~": "!~", "!~": "|~"}
+	for op, want := range filters {
+		f := LineFilter{Operator: op, Text: "error"}
+		if got := f.Negate(); got.Operator != want || got.Text != "error" {
+			t.Errorf("LineFilter{%s}.Negate() = %+v, want operator %s", op, got, want)
+		}
+	}
+}
+
+func TestParseReviewAction(t *testing.T) {
+	tests := []struct {
+		answer    string
+		count     int
+		wantErr   bool
+		wantIndex int
+		want      string
+	}{
+		{answer: "", count: 2, want: ActionDone},
+		{answer: "a", count: 2, want: ActionAdd},
+		{answer: "Y", count: 2, want: ActionAdd},
+		{answer: "e 2", count: 2, want: ActionEdit, wantIndex: 1},
+		{answer: "d1", count: 2, want: ActionDelete, wantIndex: 0},
+		{answer: "e", count: 1, want: ActionEdit, wantIndex: 0},
+		{answer: "n 1", count: 1, want: ActionNegate, wantIndex: 0},
+		{answer: "n", count: 1, want: ActionDone},
+		{answer: "No", count: 2, want: ActionDone},
+		{answer: "d", count: 1, wantErr: true},
+		{answer: "n2", count: 1, wantErr: true},
+		{answer: "e 3", count: 2, wantErr: true},
+		{answer: "a 1", count: 2, wantErr: true},
+		{answer: "x", count: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.answer, func(t *testing.T) {
+			got, index, err := parseReviewAction(tt.answer, tt.count)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseReviewAction(%q) expected error, got %s %d", tt.answer, got, index)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseReviewAction(%q) error = %v", tt.answer, err)
+			}
+			if got != tt.want || index != tt.wantIndex {
+				t.Errorf("parseReviewAction(%q) = %s %d, want %s %d", tt.answer, got, index, tt.want, tt.wantIndex)
+			}
+		})
+	}
+}
+
+func TestQueryStringStages(t *testing.T) {
+	query := Query{
+		Selectors:   []LabelSelector{{Label: "app", Operator: "=", Value: "nginx"}},
+		Metadata:    []LabelSelector{{Label: "trace_id", Operator: "=", Value: "abc"}},
+		LineFilters: []LineFilter{{Operator: "|=", Text: "error"}},
+		Parser:      ParserJSON,
+		LabelFormats: []LabelFormat{
+			{Dst: "svc", Src: "app"},
+			{Dst: "who", Src: `{{ .user | default "anon" }}`, Template: true},
+		},
+		LineFormat: "{{.level}} {{.msg}}",
+		Drop: []LabelSelector{
+			{Label: "req_id"},
+			{Label: "level", Operator: "=", Value: "debug"},
+		},
+		Keep: []LabelSelector{{Label: "app"}, {Label: "level"}},
+	}
+
+	want := `{app="nginx"} | trace_id="abc" |= "error" | json | label_format svc=app, who=` + "`" + `{{ .user | default "anon" }}` + "`" +
+		` | line_format "{{.level}} {{.msg}}" | drop req_id, level="debug" | keep app, level`
+	if got := query.String(); got != want {
+		t.Errorf("Query.String() = %s, want %s", got, want)
+	}
+}
+
+func TestLineFilterHelpers(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  func() (LineFilter, error)
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "case-insensitive",
+			filter: func() (LineFilter, error) { return caseInsensitiveFilter("time.out (db)"), nil },
+			want:   "|~ `(?i)time\\.out \\(db\\)`",
+		},
+		{
+			name:   "any of",
+			filter: func() (LineFilter, error) { return anyOfFilter("timeout, refused,,reset ") },
+			want:   `|= "timeout" or "refused" or "reset"`,
+		},
+		{
+			name:   "any of a single term",
+			filter: func() (LineFilter, error) { return anyOfFilter("timeout") },
+			want:   `|= "timeout"`,
+		},
+		{
+			name:    "any of nothing",
+			filter:  func() (LineFilter, error) { return anyOfFilter(" , ") },
+			wantErr: true,
+		},
+		{
+			name:   "ip cidr",
+			filter: func() (LineFilter, error) { return ipFilter("10.0.0.0/8") },
+			want:   `|= ip("10.0.0.0/8")`,
+		},
+		{
+			name:   "ip range",
+			filter: func() (LineFilter, error) { return ipFilter("192.168.0.1-192.168.0.9") },
+			want:   `|= ip("192.168.0.1-192.168.0.9")`,
+		},
+		{
+			name:   "ipv6 address",
+			filter: func() (LineFilter, error) { return ipFilter("::1") },
+			want:   `|= ip("::1")`,
+		},
+		{
+			name:    "invalid cidr",
+			filter:  func() (LineFilter, error) { return ipFilter("10.0.0.0/33") },
+			wantErr: true,
+		},
+		{
+			name:    "not an address",
+			filter:  func() (LineFilter, error) { return ipFilter("nginx") },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := tt.filter()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := f.String(); !tt.wantErr && got != tt.want {
+				t.Errorf("String() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	negated := LineFilter{Operator: "|=", Text: "10.0.0.0/8", IP: true}.Negate()
+	if got, want := negated.String(), `!= ip("10.0.0.0/8")`; got != want {
+		t.Errorf("Negate().String() = %s, want %s", got, want)
+	}
+}
+
+func TestQueryStringQuoting(t *testing.T) {
+	query := Query{
+		Selectors: []LabelSelector{
+			{Label: "app", Operator: "=", Value: "nginx"},
+			{Label: "path", Operator: "=~", Value: `C:\logs\.*`},
+			{Label: "title", Operator: "=", Value: `say "hi"`},
+		},
+		LineFilters: []LineFilter{
+			{Operator: "|~", Text: `5\d{2}`},
+			{Operator: "!=", Text: `say "hi"`},
+		},
+	}
+
+	// Selector values and line filter text follow the same rule: backticks
+	// when they avoid escaping, double quotes otherwise
+	want := "{app=\"nginx\",path=~`C:\\logs\\.*`,title=`say \"hi\"`} |~ `5\\d{2}` != `say \"hi\"`"
+	if got := query.String(); got != want {
+		t.Errorf("String() = %s, want %s", got, want)
+	}
+
+	both := LabelSelector{Label: "v", Operator: "=", Value: "a`b\\c"}
+	if got, want := both.String(), `v="a`+"`"+`b\\c"`; got != want {
+		t.Errorf("String() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Output formats supported by -format in -exec mode
+const (
+	FormatDefault = "default"
+	FormatRaw     = "raw"
+	FormatJSONL   = "jsonl"
+	FormatCSV     = "csv"
+	FormatColor   = "color"
+)
+
+var outputFormats = []string{FormatDefault, FormatRaw, FormatJSONL, FormatCSV, FormatColor}
+
+// ANSI escape sequences used by the color format
+const (
+	colorReset  = "\x1b[0m"
+	colorDim    = "\x1b[2m"
+	colorRed    = "\x1b[31m"
+	colorYellow = "\x1b[33m"
+	colorBlue   = "\x1b[34m"
+	colorCyan   = "\x1b[36m"
+	colorMatch  = "\x1b[1;31m"
+)
+
+var logLevelPattern = regexp.MustCompile(`(?i)\b(fatal|panic|crit(?:ical)?|error|err|warn(?:ing)?|info|debug|trace)\b`)
+
+// LogEntry is a single log line as emitted by 'logcli query --output=jsonl'
+type LogEntry struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Labels    map[string]string `json:"labels"`
+	Line      string            `json:"line"`
+}
+
+// Renderer writes log entries in one of the supported output formats
+type Renderer struct {
+	w         io.Writer
+	format    string
+	loc       *time.Location
+	highlight *regexp.Regexp
+	csv       *csv.Writer
+}
+
+// validateFormat checks that format is one of the supported output formats
+func validateFormat(format string) error {
+	for _, f := range outputFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid format: %s (expected one of %s)", format, strings.Join(outputFormats, ", "))
+}
+
+// newRenderer creates a renderer for the given format
+// Timestamps are shown in UTC when utc is set, otherwise in local time
+func newRenderer(w io.Writer, format string, utc bool, lineFilters []LineFilter) (*Renderer, error) {
+	if err := validateFormat(format); err != nil {
+		return nil, err
+	}
+
+	r := &Renderer{
+		w:      w,
+		format: format,
+		loc:    time.Local,
+	}
+	if utc {
+		r.loc = time.UTC
+	}
+
+	if format == FormatColor {
+		r.highlight = highlightPattern(lineFilters)
+	}
+
+	if format == FormatCSV {
+		r.csv = csv.NewWriter(w)
+		if err := r.csv.Write([]string{"timestamp", "labels", "line"}); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// newAppendRenderer creates a renderer continuing the output of an earlier
+// one, without repeating the CSV header
+func newAppendRenderer(w io.Writer, format string, utc bool) (*Renderer, error) {
+	r, err := newRenderer(io.Discard, format, utc, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.w = w
+	if r.csv != nil {
+		r.csv = csv.NewWriter(w)
+	}
+	return r, nil
+}
+
+// highlightPattern returns the pattern matching the text selected by the positive line filters
+func highlightPattern(lineFilters []LineFilter) *regexp.Regexp {
+	patterns := []string{}
+	for _, f := range lineFilters {
+		if f.Text == "" {
+			continue
+		}
+
+		terms := append([]string{f.Text}, f.Or...)
+		switch {
+		case f.IP:
+			// ip() matches addresses, not the text of the filter
+		case f.Operator == "|=":
+			for _, term := range terms {
+				patterns = append(patterns, regexp.QuoteMeta(term))
+			}
+		case f.Operator == "|~":
+			// Loki uses RE2 as well, so a valid filter compiles here too
+			for _, term := range terms {
+				if _, err := regexp.Compile(term); err == nil {
+					patterns = append(patterns, "(?:"+term+")")
+				}
+			}
+		default:
+			// Negative filters never match the lines that are shown
+		}
+	}
+
+	if len(patterns) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(strings.Join(patterns, "|"))
+}
+
+// Render writes a single log entry
+func (r *Renderer) Render(e LogEntry) error {
+	ts := e.Timestamp.In(r.loc).Format(time.RFC3339Nano)
+
+	switch r.format {
+	case FormatRaw:
+		_, err := fmt.Fprintln(r.w, e.Line)
+		return err
+	case FormatJSONL:
+		e.Timestamp = e.Timestamp.In(r.loc)
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(r.w, string(data))
+		return err
+	case FormatCSV:
+		return r.csv.Write([]string{ts, formatLabels(e.Labels), e.Line})
+	case FormatColor:
+		_, err := fmt.Fprintf(r.w, "%s%s%s %s%s%s %s\n",
+			colorDim, ts, colorReset,
+			colorBlue, formatLabels(e.Labels), colorReset,
+			r.colorizeLine(e.Line))
+		return err
+	default:
+		_, err := fmt.Fprintf(r.w, "%s %s %s\n", ts, formatLabels(e.Labels), e.Line)
+		return err
+	}
+}
+
+// Flush writes any buffered output
+func (r *Renderer) Flush() error {
+	if r.csv != nil {
+		r.csv.Flush()
+		return r.csv.Error()
+	}
+	return nil
+}
+
+// colorizeLine highlights the line filter match and log level keywords
+func (r *Renderer) colorizeLine(line string) string {
+	if r.highlight != nil {
+		line = r.highlight.ReplaceAllStringFunc(line, func(m string) string {
+			return colorMatch + m + colorReset
+		})
+	}
+
+	return logLevelPattern.ReplaceAllStringFunc(line, func(m string) string {
+		return levelColor(m) + m + colorReset
+	})
+}
+
+// levelColor returns the color for a log level keyword
+func levelColor(level string) string {
+	switch strings.ToLower(level)[0] {
+	case 'f', 'p', 'c', 'e':
+		return colorRed
+	case 'w':
+		return colorYellow
+	case 'i':
+		return colorCyan
+	default:
+		return colorDim
+	}
+}
+
+// formatLabels formats a label set as a LogQL stream selector with sorted keys
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", k, labels[k]))
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
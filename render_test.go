@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderer(t *testing.T) {
+	entry := LogEntry{
+		Timestamp: time.Date(2025, 8, 14, 1, 0, 0, 0, time.UTC),
+		Labels:    map[string]string{"env": "production", "app": "nginx"},
+		Line:      `connect error: "refused"`,
+	}
+
+	tests := []struct {
+		name        string
+		format      string
+		lineFilters []LineFilter
+		want        string
+	}{
+		{
+			name:   "default",
+			format: FormatDefault,
+			want:   "2025-08-14T01:00:00Z {app=\"nginx\", env=\"production\"} connect error: \"refused\"\n",
+		},
+		{
+			name:   "raw",
+			format: FormatRaw,
+			want:   "connect error: \"refused\"\n",
+		},
+		{
+			name:   "jsonl",
+			format: FormatJSONL,
+			want:   `{"timestamp":"2025-08-14T01:00:00Z","labels":{"app":"nginx","env":"production"},"line":"connect error: \"refused\""}` + "\n",
+		},
+		{
+			name:   "csv",
+			format: FormatCSV,
+			want:   "timestamp,labels,line\n2025-08-14T01:00:00Z,\"{app=\"\"nginx\"\", env=\"\"production\"\"}\",\"connect error: \"\"refused\"\"\"\n",
+		},
+		{
+			name:        "color highlights filter match and level",
+			format:      FormatColor,
+			lineFilters: []LineFilter{{Operator: "|=", Text: "refused"}, {Operator: "!=", Text: "debug"}},
+			want: colorDim + "2025-08-14T01:00:00Z" + colorReset + " " +
+				colorBlue + `{app="nginx", env="production"}` + colorReset + " " +
+				`connect ` + colorRed + "error" + colorReset + `: "` + colorMatch + "refused" + colorReset + "\"\n",
+		},
+		{
+			name:        "color highlights any of several terms",
+			format:      FormatColor,
+			lineFilters: []LineFilter{{Operator: "|=", Text: "timeout", Or: []string{"refused"}}},
+			want: colorDim + "2025-08-14T01:00:00Z" + colorReset + " " +
+				colorBlue + `{app="nginx", env="production"}` + colorReset + " " +
+				`connect ` + colorRed + "error" + colorReset + `: "` + colorMatch + "refused" + colorReset + "\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			r, err := newRenderer(&buf, tt.format, true, tt.lineFilters)
+			if err != nil {
+				t.Fatalf("newRenderer() error = %v", err)
+			}
+			if err := r.Render(entry); err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if err := r.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRendererInvalidFormat(t *testing.T) {
+	if _, err := newRenderer(&bytes.Buffer{}, "xml", false, nil); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestScanLogEntries(t *testing.T) {
+	input := `{"labels":{"app":"nginx"},"line":"first","timestamp":"2025-08-14T09:00:00.123456789+09:00"}
+
+{"labels":{"app":"nginx"},"line":"second","timestamp":"2025-08-14T09:00:01+09:00"}
+`
+
+	var lines []string
+	err := scanLogEntries(strings.NewReader(input), func(e LogEntry) error {
+		lines = append(lines, e.Line)
+		if e.Labels["app"] != "nginx" {
+			t.Errorf("unexpected labels: %v", e.Labels)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("scanLogEntries() error = %v", err)
+	}
+	if strings.Join(lines, ",") != "first,second" {
+		t.Errorf("scanLogEntries() lines = %v", lines)
+	}
+
+	if err := scanLogEntries(strings.NewReader("not json\n"), func(LogEntry) error { return nil }); err == nil {
+		t.Error("expected error for malformed output")
+	}
+}
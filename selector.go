@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Selector backends selected with -selector, LOQUI_SELECTOR or the config file
+const (
+	SelectorAuto    = "auto"
+	SelectorFzf     = "fzf"
+	SelectorSkim    = "sk"
+	SelectorPeco    = "peco"
+	SelectorGum     = "gum"
+	SelectorBuiltin = "builtin"
+)
+
+// externalSelectors are tried in this order when the backend is auto
+var externalSelectors = []string{SelectorFzf, SelectorSkim, SelectorPeco, SelectorGum}
+
+// builtinPageSize is the number of candidates the built-in picker shows at once
+const builtinPageSize = 20
+
+// Selector picks items from a list interactively
+type Selector interface {
+	Select(items []string, prompt string) (string, error)
+	SelectMulti(items []string, prompt string) ([]string, error)
+}
+
+// newSelector returns the named backend; auto picks the first installed
+// external picker and falls back to the built-in one
+func newSelector(name string) (Selector, error) {
+	switch name {
+	case "", SelectorAuto:
+		for _, candidate := range externalSelectors {
+			if _, err := exec.LookPath(candidate); err == nil {
+				return newSelector(candidate)
+			}
+		}
+		return builtinSelector{}, nil
+	case SelectorBuiltin:
+		return builtinSelector{}, nil
+	case SelectorFzf, SelectorSkim:
+		return commandSelector{name: name, multi: []string{"--multi"}, args: func(prompt string) []string {
+			return []string{"--prompt", prompt}
+		}}, nil
+	case SelectorPeco:
+		// peco always allows marking several lines with Ctrl-Space
+		return commandSelector{name: name, args: func(prompt string) []string {
+			return []string{"--prompt", prompt}
+		}}, nil
+	case SelectorGum:
+		return commandSelector{name: name, multi: []string{"--no-limit"}, args: func(prompt string) []string {
+			return []string{"filter", "--header", prompt}
+		}}, nil
+	default:
+		return nil, fmt.Errorf("invalid selector: %s (expected auto, fzf, sk, peco, gum or builtin)", name)
+	}
+}
+
+// commandSelector runs an external fuzzy finder reading items from stdin
+type commandSelector struct {
+	name  string
+	args  func(prompt string) []string
+	multi []string // Extra arguments allowing several selections
+}
+
+func (s commandSelector) Select(items []string, prompt string) (string, error) {
+	selected, err := s.run(items, s.args(prompt))
+	if err != nil {
+		return "", err
+	}
+	return selected[0], nil
+}
+
+func (s commandSelector) SelectMulti(items []string, prompt string) ([]string, error) {
+	return s.run(items, append(s.args(prompt), s.multi...))
+}
+
+// run returns the lines printed by the finder
+func (s commandSelector) run(items []string, args []string) ([]string, error) {
+	cmd := exec.Command(s.name, args...)
+	cmd.Stdin = strings.NewReader(strings.Join(items, "\n"))
+	cmd.Stderr = os.Stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", s.name, err)
+	}
+
+	selected := []string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			selected = append(selected, line)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no selection made")
+	}
+
+	return selected, nil
+}
+
+// builtinSelector is a line based fuzzy picker for systems without an
+// external finder. Typing text narrows the list, #N picks an item. Numbers
+// without # are filter text, label values like 404 or 8080 are common.
+type builtinSelector struct{}
+
+func (builtinSelector) Select(items []string, prompt string) (string, error) {
+	matches := items
+	for {
+		fmt.Printf("\n%s\n", prompt)
+		for i, item := range matches {
+			if i == builtinPageSize {
+				fmt.Printf("... %d more, type to narrow down\n", len(matches)-builtinPageSize)
+				break
+			}
+			fmt.Printf("%d. %s\n", i+1, item)
+		}
+		fmt.Print("Enter #number, text to filter, or press Enter for 1: ")
+
+		answer, err := inputText("")
+		if err != nil {
+			return "", err
+		}
+
+		if answer == "" {
+			if len(matches) == 0 {
+				return "", fmt.Errorf("no selection made")
+			}
+			return matches[0], nil
+		}
+
+		if number, ok := strings.CutPrefix(answer, "#"); ok {
+			num, err := strconv.Atoi(strings.TrimSpace(number))
+			if err != nil || num < 1 || num > len(matches) || num > builtinPageSize {
+				fmt.Printf("Invalid number: %s\n", number)
+				continue
+			}
+			return matches[num-1], nil
+		}
+
+		filtered := fuzzyFilter(items, answer)
+		switch len(filtered) {
+		case 0:
+			fmt.Printf("No match for '%s'\n", answer)
+			matches = items
+		case 1:
+			return filtered[0], nil
+		default:
+			matches = filtered
+		}
+	}
+}
+
+// SelectMulti shows all items numbered; numbers and ranges like #1,3-5 pick
+// items, other text narrows the list
+func (builtinSelector) SelectMulti(items []string, prompt string) ([]string, error) {
+	matches := items
+	for {
+		fmt.Printf("\n%s\n", prompt)
+		for i, item := range matches {
+			fmt.Printf("%d. %s\n", i+1, item)
+		}
+		fmt.Print("Enter #numbers (e.g. #1,3-5), text to filter, or 'all': ")
+
+		answer, err := inputText("")
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case answer == "":
+			return nil, fmt.Errorf("no selection made")
+		case strings.EqualFold(answer, "all"):
+			return matches, nil
+		}
+
+		if numbers, ok := strings.CutPrefix(answer, "#"); ok {
+			indexes, err := parseNumberList(numbers, len(matches))
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			selected := make([]string, len(indexes))
+			for i, index := range indexes {
+				selected[i] = matches[index]
+			}
+			return selected, nil
+		}
+
+		filtered := fuzzyFilter(items, answer)
+		if len(filtered) == 0 {
+			fmt.Printf("No match for '%s'\n", answer)
+			matches = items
+			continue
+		}
+		matches = filtered
+	}
+}
+
+// parseNumberList parses 1-based numbers and ranges like "1,3-5" into
+// 0-based indexes below count, in the order given
+func parseNumberList(s string, count int) ([]int, error) {
+	indexes := []int{}
+	seen := map[int]bool{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		first, last, isRange := strings.Cut(part, "-")
+
+		from, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil {
+			return nil, fmt.Errorf("invalid number: %s", part)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(strings.TrimSpace(last)); err != nil {
+				return nil, fmt.Errorf("invalid range: %s", part)
+			}
+		}
+		if from < 1 || to > count || from > to {
+			return nil, fmt.Errorf("invalid range: %s (expected 1-%d)", part, count)
+		}
+
+		for n := from; n <= to; n++ {
+			if !seen[n-1] {
+				seen[n-1] = true
+				indexes = append(indexes, n-1)
+			}
+		}
+	}
+	return indexes, nil
+}
+
+// fuzzyFilter returns the items matching pattern, best matches first
+func fuzzyFilter(items []string, pattern string) []string {
+	type scored struct {
+		item  string
+		score int
+	}
+
+	matched := []scored{}
+	for _, item := range items {
+		if score, ok := fuzzyMatch(pattern, item); ok {
+			matched = append(matched, scored{item, score})
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].score > matched[j].score
+	})
+
+	result := make([]string, len(matched))
+	for i, m := range matched {
+		result[i] = m.item
+	}
+	return result
+}
+
+// fuzzyMatch reports whether the characters of pattern appear in item in order,
+// ignoring case. Consecutive characters, word starts and a match at the start
+// of item score higher.
+func fuzzyMatch(pattern, item string) (int, bool) {
+	p := []rune(strings.ToLower(pattern))
+	s := []rune(strings.ToLower(item))
+
+	score := 0
+	pi := 0
+	prev := -2
+	for si := 0; si < len(s) && pi < len(p); si++ {
+		if s[si] != p[pi] {
+			continue
+		}
+
+		score++
+		if si == prev+1 {
+			score += 2
+		}
+		if si == 0 {
+			score += 5
+		} else if !unicode.IsLetter(s[si-1]) && !unicode.IsDigit(s[si-1]) {
+			score += 3
+		}
+		prev = si
+		pi++
+	}
+
+	if pi < len(p) {
+		return 0, false
+	}
+	// Prefer shorter items among equally good matches
+	return score*100 - len(s), true
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		item    string
+		want    bool
+	}{
+		{pattern: "nsp", item: "namespace", want: true},
+		{pattern: "APP", item: "app", want: true},
+		{pattern: "svn", item: "service_name", want: true},
+		{pattern: "pa", item: "app", want: false},
+		{pattern: "envx", item: "env", want: false},
+	}
+
+	for _, tt := range tests {
+		if _, got := fuzzyMatch(tt.pattern, tt.item); got != tt.want {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, want %v", tt.pattern, tt.item, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzyFilter(t *testing.T) {
+	items := []string{"container_name", "namespace", "app", "name", "pod_name"}
+
+	got := fuzzyFilter(items, "name")
+	want := []string{"name", "namespace", "pod_name", "container_name"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fuzzyFilter() = %v, want %v", got, want)
+	}
+}
+
+func TestNewSelector(t *testing.T) {
+	if s, err := newSelector(SelectorBuiltin); err != nil || s != (builtinSelector{}) {
+		t.Errorf("newSelector(builtin) = %v, %v", s, err)
+	}
+
+	for _, name := range []string{SelectorFzf, SelectorSkim, SelectorPeco, SelectorGum} {
+		s, err := newSelector(name)
+		if err != nil {
+			t.Fatalf("newSelector(%s) error = %v", name, err)
+		}
+		if cs, ok := s.(commandSelector); !ok || cs.name != name {
+			t.Errorf("newSelector(%s) = %#v", name, s)
+		}
+	}
+
+	// Without any external finder in PATH, auto falls back to the built-in picker
+	t.Setenv("PATH", t.TempDir())
+	if s, err := newSelector(SelectorAuto); err != nil || s != (builtinSelector{}) {
+		t.Errorf("newSelector(auto) = %v, %v", s, err)
+	}
+
+	if _, err := newSelector("dmenu"); err == nil {
+		t.Error("expected error for unknown selector")
+	}
+}
+
+func TestParseNumberList(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []int
+		wantErr bool
+	}{
+		{input: "1", want: []int{0}},
+		{input: "3, 1", want: []int{2, 0}},
+		{input: "2-4,1,3", want: []int{1, 2, 3, 0}},
+		{input: "0", wantErr: true},
+		{input: "6", wantErr: true},
+		{input: "4-2", wantErr: true},
+		{input: "1-x", wantErr: true},
+		{input: "app", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseNumberList(tt.input, 5)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseNumberList() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseNumberList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuiltinSelectorNumbers(t *testing.T) {
+	items := []string{"200", "404", "500", "8080"}
+	tests := []struct {
+		answer string
+		want   string
+	}{
+		{"#2", "404"},
+		{"404", "404"},
+		{"808", "8080"},
+		// Without # a number filters, item 2 would be 404
+		{"2", "200"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.answer, func(t *testing.T) {
+			withStdin(t, tt.answer+"\n")
+			got, err := builtinSelector{}.Select(items, "Select value:")
+			if err != nil {
+				t.Fatalf("Select() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Select(%q) = %s, want %s", tt.answer, got, tt.want)
+			}
+		})
+	}
+
+	withStdin(t, "#1,3\n")
+	got, err := builtinSelector{}.SelectMulti(items, "Select values:")
+	if want := []string{"200", "500"}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("SelectMulti() = %v, %v, want %v", got, err, want)
+	}
+}
+
+// withStdin feeds input to inputText and silences the prompts for the test
+func withStdin(t *testing.T, input string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString(input)
+	w.Close()
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdin, stdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = r, devNull
+	t.Cleanup(func() {
+		os.Stdin, os.Stdout = stdin, stdout
+		r.Close()
+		devNull.Close()
+	})
+}
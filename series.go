@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// defaultSeriesSince is the time range loqui series looks at by default
+const defaultSeriesSince = "1h"
+
+// seriesLabel summarizes one label across a set of series
+type seriesLabel struct {
+	Label  string
+	Series int // Series having the label
+	Values int // Distinct values of the label
+}
+
+// parseSeriesMatchers parses matchers given as arguments, with or without
+// braces: app="nginx" env=~"prod|staging" or '{app="nginx", env="prod"}'
+func parseSeriesMatchers(args []string) ([]LabelSelector, error) {
+	parts := []string{}
+	for _, arg := range args {
+		arg = strings.TrimSpace(arg)
+		arg = strings.TrimSuffix(strings.TrimPrefix(arg, "{"), "}")
+		if arg != "" {
+			parts = append(parts, arg)
+		}
+	}
+
+	matchers, err := parseConditions(strings.Join(parts, ","))
+	if err != nil {
+		return nil, err
+	}
+	if len(matchers) == 0 {
+		return nil, fmt.Errorf("no label matchers given")
+	}
+	return matchers, nil
+}
+
+// summarizeSeries counts the series and distinct values of each label,
+// most common labels first
+func summarizeSeries(series []map[string]string) []seriesLabel {
+	counts := map[string]int{}
+	values := map[string]map[string]bool{}
+	for _, labels := range series {
+		for name, value := range labels {
+			counts[name]++
+			if values[name] == nil {
+				values[name] = map[string]bool{}
+			}
+			values[name][value] = true
+		}
+	}
+
+	summary := make([]seriesLabel, 0, len(counts))
+	for name, count := range counts {
+		summary = append(summary, seriesLabel{Label: name, Series: count, Values: len(values[name])})
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		if summary[i].Series != summary[j].Series {
+			return summary[i].Series > summary[j].Series
+		}
+		return summary[i].Label < summary[j].Label
+	})
+	return summary
+}
+
+// seriesSelector returns the matchers selecting exactly one series
+func seriesSelector(labels map[string]string) []LabelSelector {
+	selectors := make([]LabelSelector, 0, len(labels))
+	for name, value := range labels {
+		selectors = append(selectors, LabelSelector{Label: name, Operator: "=", Value: value})
+	}
+	sort.Slice(selectors, func(i, j int) bool {
+		return selectors[i].Label < selectors[j].Label
+	})
+	return selectors
+}
+
+// seriesItems lists the series for printing and picking, sorted
+func seriesItems(series []map[string]string) []string {
+	items := make([]string, len(series))
+	for i, labels := range series {
+		items[i] = formatLabels(labels)
+	}
+	sort.Strings(items)
+	return items
+}
+
+// printSeriesSummary writes the label table of the series
+func printSeriesSummary(w io.Writer, series []map[string]string) error {
+	fmt.Fprintf(w, "%d series\n\n", len(series))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LABEL\tSERIES\tVALUES")
+	for _, l := range summarizeSeries(series) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", l.Label, l.Series, l.Values)
+	}
+	return tw.Flush()
+}
+
+const seriesUsage = `Usage:
+  loqui series [options] <matcher> ...
+
+Lists the streams matching the label matchers, e.g. app="nginx", with the
+number of series and distinct values of each label.
+
+Options:
+  -since       Time range to look at, e.g. 30m, 6h or 2d (default: 1h)
+  -pick        Pick a series with the fuzzy finder and print it as a
+               stream selector
+  -selector    Fuzzy finder for -pick: auto, fzf, sk, peco, gum, builtin
+               (default: $LOQUI_SELECTOR, the config file, or auto)
+  -config      Configuration file
+               (default: ~/.config/loqui/config.json)
+`
+
+// runSeries implements the series subcommand and returns the exit status
+func runSeries(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("series", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, seriesUsage)
+	}
+	since := flags.String("since", defaultSeriesSince, "Time range to look at")
+	pick := flags.Bool("pick", false, "Pick a series and print it as a stream selector")
+	selectorName := flags.String("selector", "", "Fuzzy finder backend")
+	configPath := flags.String("config", "", "Configuration file")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	matchers, err := parseSeriesMatchers(flags.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		fmt.Fprint(stderr, seriesUsage)
+		return 2
+	}
+
+	d, err := parseSince(*since)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: invalid range: %v\n", err)
+		return 2
+	}
+
+	// -selector wins over LOQUI_SELECTOR, which wins over the config file
+	var selector Selector
+	if *pick {
+		fileConfig, err := loadFileConfig(configFile(*configPath), *configPath != "")
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 2
+		}
+		selector, err = newSelector(firstNonEmpty(*selectorName, os.Getenv("LOQUI_SELECTOR"), fileConfig.Selector))
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 2
+		}
+	}
+
+	lokiAddr := os.Getenv("LOKI_ADDR")
+	if lokiAddr == "" {
+		fmt.Fprintf(stderr, "Error: LOKI_ADDR environment variable is not set\n")
+		return 2
+	}
+
+	end := time.Now()
+	client := newLokiClient(lokiAddr, os.Getenv)
+	query := Query{Selectors: matchers}.String()
+	series, err := lookup("Fetching series...", func(ctx context.Context) ([]map[string]string, error) {
+		ctx, cancel := context.WithTimeout(ctx, defaultLookupTimeout)
+		defer cancel()
+		return client.Series(ctx, query, end.Add(-d), end)
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		if hint := errorHint(err); hint != "" {
+			fmt.Fprintf(stderr, "Hint: %s\n", hint)
+		}
+		return 1
+	}
+	if len(series) == 0 {
+		fmt.Fprintf(stderr, "No series match %s in the last %s\n", query, *since)
+		return 1
+	}
+
+	if !*pick {
+		if err := printSeriesSummary(stdout, series); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout)
+		for _, item := range seriesItems(series) {
+			fmt.Fprintln(stdout, item)
+		}
+		return 0
+	}
+
+	// The table helps choosing, the selector is the only output
+	if err := printSeriesSummary(stderr, series); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	byItem := map[string]map[string]string{}
+	for _, labels := range series {
+		byItem[formatLabels(labels)] = labels
+	}
+	item, err := selector.Select(seriesItems(series), "Select series:")
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: series selection failed: %v\n", err)
+		return 1
+	}
+	labels, ok := byItem[item]
+	if !ok {
+		fmt.Fprintf(stderr, "Error: unknown series: %s\n", item)
+		return 1
+	}
+	fmt.Fprintln(stdout, Query{Selectors: seriesSelector(labels)})
+	return 0
+}
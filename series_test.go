@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSeriesMatchers(t *testing.T) {
+	got, err := parseSeriesMatchers([]string{`{app="nginx", env=~"prod|staging"}`, ` namespace!="kube-system"`})
+	if err != nil {
+		t.Fatalf("parseSeriesMatchers() error = %v", err)
+	}
+	want := []LabelSelector{
+		{Label: "app", Operator: "=", Value: "nginx"},
+		{Label: "env", Operator: "=~", Value: "prod|staging"},
+		{Label: "namespace", Operator: "!=", Value: "kube-system"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSeriesMatchers() = %v, want %v", got, want)
+	}
+
+	for _, args := range [][]string{nil, {"{}"}, {"app=nginx"}} {
+		if _, err := parseSeriesMatchers(args); err == nil {
+			t.Errorf("parseSeriesMatchers(%q) expected error", args)
+		}
+	}
+}
+
+func TestSummarizeSeries(t *testing.T) {
+	series := []map[string]string{
+		{"app": "nginx", "env": "prod", "pod": "nginx-1"},
+		{"app": "nginx", "env": "prod", "pod": "nginx-2"},
+		{"app": "api", "env": "staging"},
+	}
+	want := []seriesLabel{
+		{Label: "app", Series: 3, Values: 2},
+		{Label: "env", Series: 3, Values: 2},
+		{Label: "pod", Series: 2, Values: 2},
+	}
+	if got := summarizeSeries(series); !reflect.DeepEqual(got, want) {
+		t.Errorf("summarizeSeries() = %v, want %v", got, want)
+	}
+}
+
+func TestSeriesSelector(t *testing.T) {
+	got := Query{Selectors: seriesSelector(map[string]string{"pod": "nginx-1", "app": "nginx"})}.String()
+	if want := `{app="nginx",pod="nginx-1"}`; got != want {
+		t.Errorf("seriesSelector() = %s, want %s", got, want)
+	}
+
+	// Values from the API are quoted, the printed selector must be valid LogQL
+	got = Query{Selectors: seriesSelector(map[string]string{"filename": `C:\logs\a.log`, "path": `a"b`})}.String()
+	if want := "{filename=`C:\\logs\\a.log`,path=`a\"b`}"; got != want {
+		t.Errorf("seriesSelector() = %s, want %s", got, want)
+	}
+	selectors, _, err := parseLogQLForLint(got)
+	if err != nil {
+		t.Fatalf("parseLogQLForLint(%s) error = %v", got, err)
+	}
+	if selectors[0].Value != `C:\logs\a.log` || selectors[1].Value != `a"b` {
+		t.Errorf("selector values read back as %v", selectors)
+	}
+}
+
+func TestRunSeries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != seriesPath {
+			t.Errorf("path = %s, want %s", r.URL.Path, seriesPath)
+		}
+		if got := r.URL.Query().Get("match[]"); got != `{app="nginx"}` {
+			t.Errorf("match[] = %s", got)
+		}
+		w.Write([]byte(`{"status":"success","data":[
+			{"app":"nginx","pod":"nginx-2"},
+			{"app":"nginx","pod":"nginx-1"}
+		]}`))
+	}))
+	defer server.Close()
+	t.Setenv("LOKI_ADDR", server.URL)
+
+	var stdout, stderr bytes.Buffer
+	if status := runSeries([]string{"-since", "6h", `app="nginx"`}, &stdout, &stderr); status != 0 {
+		t.Fatalf("runSeries() status = %d, stderr %q", status, stderr.String())
+	}
+
+	want := []string{
+		"2 series",
+		"",
+		"LABEL  SERIES  VALUES",
+		"app    2       1",
+		"pod    2       2",
+		"",
+		`{app="nginx", pod="nginx-1"}`,
+		`{app="nginx", pod="nginx-2"}`,
+	}
+	if got := strings.Split(strings.TrimSpace(stdout.String()), "\n"); !reflect.DeepEqual(got, want) {
+		t.Errorf("runSeries() output = %q, want %q", got, want)
+	}
+
+	if status := runSeries(nil, &stdout, &stderr); status != 2 {
+		t.Errorf("runSeries() without matchers status = %d, want 2", status)
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Output formats of loqui stats labels
+const (
+	StatsFormatTable = "table"
+	StatsFormatJSON  = "json"
+)
+
+// defaultTopValues is the number of most common values reported per label
+const defaultTopValues = 5
+
+// valueCount is a label value and the number of series having it
+type valueCount struct {
+	Value  string `json:"value"`
+	Series int    `json:"series"`
+}
+
+// labelStats describes the cardinality of a label
+type labelStats struct {
+	Label     string       `json:"label"`
+	Values    int          `json:"values"` // Distinct values
+	Series    int          `json:"series"` // Series having the label
+	TopValues []valueCount `json:"top_values"`
+}
+
+// topValues returns the n values of label found in the most series
+func topValues(series []map[string]string, label string, n int) []valueCount {
+	counts := map[string]int{}
+	for _, labels := range series {
+		if value, ok := labels[label]; ok {
+			counts[value]++
+		}
+	}
+
+	top := make([]valueCount, 0, len(counts))
+	for value, count := range counts {
+		top = append(top, valueCount{Value: value, Series: count})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Series != top[j].Series {
+			return top[i].Series > top[j].Series
+		}
+		return top[i].Value < top[j].Value
+	})
+	return top[:min(n, len(top))]
+}
+
+// selectorLabelStats returns the stats of every label of the series
+// matching a selector
+func selectorLabelStats(series []map[string]string, top int) []labelStats {
+	stats := []labelStats{}
+	for _, l := range summarizeSeries(series) {
+		stats = append(stats, labelStats{
+			Label:     l.Label,
+			Values:    l.Values,
+			Series:    l.Series,
+			TopValues: topValues(series, l.Label, top),
+		})
+	}
+	sortLabelStats(stats)
+	return stats
+}
+
+// sortLabelStats orders the labels with the most distinct values first
+func sortLabelStats(stats []labelStats) {
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].Values != stats[j].Values {
+			return stats[i].Values > stats[j].Values
+		}
+		return stats[i].Label < stats[j].Label
+	})
+}
+
+// collectLabelStats returns the stats of the labels of the series matching
+// selector, or of all labels when there is no selector. Label names and values
+// come from the same discovery as the interactive flow, series counts from the
+// series API, one call per label.
+func collectLabelStats(ctx context.Context, config *Config, client *lokiClient, selector []LabelSelector, start, end time.Time, top, workers int) ([]labelStats, error) {
+	// Each series call gets the lookup timeout, like the logcli discovery calls
+	getSeries := func(selector []LabelSelector) ([]map[string]string, error) {
+		ctx, cancel := context.WithTimeout(ctx, lookupTimeout(config))
+		defer cancel()
+		return client.Series(ctx, Query{Selectors: selector}.String(), start, end)
+	}
+
+	if len(selector) > 0 {
+		series, err := getSeries(selector)
+		if err != nil {
+			return nil, err
+		}
+		return selectorLabelStats(series, top), nil
+	}
+
+	labels, err := getLabels(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get labels: %w", err)
+	}
+
+	// Fetch values in the background while series are counted
+	prefetcher := newPrefetcher(config, workers)
+	defer prefetcher.Close()
+	prefetcher.Warm(labels)
+
+	stats := []labelStats{}
+	for _, label := range labels {
+		series, err := getSeries([]LabelSelector{{Label: label, Operator: "=~", Value: ".+"}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get series of %s: %w", label, err)
+		}
+		values, err := prefetcher.Values(ctx, config, label)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get values of %s: %w", label, err)
+		}
+		stats = append(stats, labelStats{
+			Label:     label,
+			Values:    len(values),
+			Series:    len(series),
+			TopValues: topValues(series, label, top),
+		})
+	}
+	sortLabelStats(stats)
+	return stats, nil
+}
+
+// printLabelStats writes the stats as a table or as JSON
+func printLabelStats(w io.Writer, stats []labelStats, format string) error {
+	if format == StatsFormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(stats)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LABEL\tVALUES\tSERIES\tTOP VALUES")
+	for _, s := range stats {
+		top := make([]string, len(s.TopValues))
+		for i, v := range s.TopValues {
+			top[i] = fmt.Sprintf("%s (%d)", v.Value, v.Series)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", s.Label, s.Values, s.Series, strings.Join(top, ", "))
+	}
+	return tw.Flush()
+}
+
+const statsUsage = `Usage:
+  loqui stats labels [options] [<matcher> ...]
+
+Reports the cardinality of labels: the number of distinct values, the
+number of series having the label and its most common values, labels with
+the most values first. With matchers, e.g. namespace="checkout", only the
+series they select are counted.
+
+Options:
+  -since       Time range to look at, e.g. 30m, 6h or 2d (default: 1h)
+  -top         Number of most common values shown per label (default: 5)
+  -format      Output format: table or json (default: table)
+  -refresh     Ignore cached labels and values and fetch them again
+`
+
+// runStats implements the stats subcommand and returns the exit status
+func runStats(args []string, stdout, stderr io.Writer) int {
+	// labels is the only report so far
+	if len(args) == 0 || args[0] != "labels" {
+		fmt.Fprint(stderr, statsUsage)
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help") {
+			return 0
+		}
+		return 2
+	}
+
+	flags := flag.NewFlagSet("stats labels", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, statsUsage)
+	}
+	since := flags.String("since", defaultSeriesSince, "Time range to look at")
+	top := flags.Int("top", defaultTopValues, "Number of most common values shown per label")
+	format := flags.String("format", StatsFormatTable, "Output format")
+	refresh := flags.Bool("refresh", false, "Ignore cached labels and values")
+	if err := flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if *format != StatsFormatTable && *format != StatsFormatJSON {
+		fmt.Fprintf(stderr, "Error: invalid format: %s (expected table or json)\n", *format)
+		return 2
+	}
+	if *top < 0 {
+		fmt.Fprintf(stderr, "Error: invalid top: %d\n", *top)
+		return 2
+	}
+
+	var selector []LabelSelector
+	if flags.NArg() > 0 {
+		matchers, err := parseSeriesMatchers(flags.Args())
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 2
+		}
+		selector = matchers
+	}
+
+	d, err := parseSince(*since)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: invalid range: %v\n", err)
+		return 2
+	}
+
+	lokiAddr := os.Getenv("LOKI_ADDR")
+	if lokiAddr == "" {
+		fmt.Fprintf(stderr, "Error: LOKI_ADDR environment variable is not set\n")
+		return 2
+	}
+
+	// Same discovery settings as the interactive flow, so the cache is shared
+	config := &Config{
+		LogCLICmd:     "logcli",
+		LokiAddr:      lokiAddr,
+		OrgID:         os.Getenv("LOKI_ORG_ID"),
+		Cache:         newLabelCache(defaultCacheDir(), defaultCacheTTL, *refresh),
+		LookupTimeout: defaultLookupTimeout,
+		TimeArgs:      []string{"--since", apiDuration(d)},
+	}
+
+	end := time.Now()
+	client := newLokiClient(lokiAddr, os.Getenv)
+	stats, err := lookup("Collecting label stats...", func(ctx context.Context) ([]labelStats, error) {
+		return collectLabelStats(ctx, config, client, selector, end.Add(-d), end, *top, defaultPrefetchWorkers)
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		if hint := errorHint(err); hint != "" {
+			fmt.Fprintf(stderr, "Hint: %s\n", hint)
+		}
+		return 1
+	}
+
+	if err := printLabelStats(stdout, stats, *format); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
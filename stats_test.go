@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTopValues(t *testing.T) {
+	series := []map[string]string{
+		{"env": "prod", "pod": "a"},
+		{"env": "prod", "pod": "b"},
+		{"env": "staging", "pod": "c"},
+		{"pod": "d"},
+	}
+	want := []valueCount{{Value: "prod", Series: 2}, {Value: "staging", Series: 1}}
+	if got := topValues(series, "env", 5); !reflect.DeepEqual(got, want) {
+		t.Errorf("topValues() = %v, want %v", got, want)
+	}
+	if got := topValues(series, "pod", 2); len(got) != 2 || got[0].Value != "a" {
+		t.Errorf("topValues() limited = %v", got)
+	}
+}
+
+func TestSelectorLabelStats(t *testing.T) {
+	series := []map[string]string{
+		{"app": "nginx", "pod": "nginx-1"},
+		{"app": "nginx", "pod": "nginx-2"},
+		{"app": "nginx", "pod": "nginx-3"},
+	}
+	want := []labelStats{
+		{Label: "pod", Values: 3, Series: 3, TopValues: []valueCount{{"nginx-1", 1}, {"nginx-2", 1}}},
+		{Label: "app", Values: 1, Series: 3, TopValues: []valueCount{{"nginx", 3}}},
+	}
+	if got := selectorLabelStats(series, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("selectorLabelStats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCollectLabelStats(t *testing.T) {
+	logcli, _ := fakeLogCLI(t, `case "$2" in
+--quiet) printf 'app\nenv\n' ;;
+app) printf 'api\nnginx\n' ;;
+env) printf 'prod\n' ;;
+esac`)
+
+	series := map[string]string{
+		`{app=~".+"}`: `[{"app":"nginx","env":"prod"},{"app":"nginx","env":"prod","pod":"x"},{"app":"api","env":"prod"}]`,
+		`{env=~".+"}`: `[{"app":"nginx","env":"prod"},{"app":"nginx","env":"prod","pod":"x"},{"app":"api","env":"prod"}]`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := series[r.URL.Query().Get("match[]")]
+		if !ok {
+			t.Errorf("unexpected match[] %s", r.URL.Query().Get("match[]"))
+		}
+		w.Write([]byte(`{"status":"success","data":` + data + `}`))
+	}))
+	defer server.Close()
+
+	config := &Config{LogCLICmd: logcli, TimeArgs: []string{"--since", "1h"}}
+	client := newLokiClient(server.URL, func(string) string { return "" })
+	end := time.Now()
+	stats, err := collectLabelStats(context.Background(), config, client, nil, end.Add(-time.Hour), end, 1, 2)
+	if err != nil {
+		t.Fatalf("collectLabelStats() error = %v", err)
+	}
+
+	want := []labelStats{
+		{Label: "app", Values: 2, Series: 3, TopValues: []valueCount{{"nginx", 2}}},
+		{Label: "env", Values: 1, Series: 3, TopValues: []valueCount{{"prod", 3}}},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("collectLabelStats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestCollectLabelStatsTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	config := &Config{LookupTimeout: 50 * time.Millisecond}
+	client := newLokiClient(server.URL, func(string) string { return "" })
+	selector := []LabelSelector{{Label: "app", Operator: "=", Value: "nginx"}}
+	end := time.Now()
+	_, err := collectLabelStats(context.Background(), config, client, selector, end.Add(-time.Hour), end, 1, 1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("collectLabelStats() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestPrintLabelStats(t *testing.T) {
+	stats := []labelStats{
+		{Label: "pod", Values: 120, Series: 120, TopValues: []valueCount{{"nginx-1", 1}, {"nginx-2", 1}}},
+		{Label: "app", Values: 1, Series: 120, TopValues: []valueCount{{"nginx", 120}}},
+	}
+
+	var table bytes.Buffer
+	if err := printLabelStats(&table, stats, StatsFormatTable); err != nil {
+		t.Fatal(err)
+	}
+	want := "LABEL  VALUES  SERIES  TOP VALUES\n" +
+		"pod    120     120     nginx-1 (1), nginx-2 (1)\n" +
+		"app    1       120     nginx (120)\n"
+	if table.String() != want {
+		t.Errorf("table output =\n%s\nwant\n%s", table.String(), want)
+	}
+
+	var out bytes.Buffer
+	if err := printLabelStats(&out, stats[1:], StatsFormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"top_values": [`) || !strings.Contains(out.String(), `"value": "nginx"`) {
+		t.Errorf("json output = %s", out.String())
+	}
+}
+
+func TestRunStatsInvalidArguments(t *testing.T) {
+	t.Setenv("LOKI_ADDR", "http://localhost:3100")
+
+	for _, args := range [][]string{
+		{},
+		{"streams"},
+		{"labels", "-format", "yaml"},
+		{"labels", "-top", "-1"},
+		{"labels", "app=nginx"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if status := runStats(args, &stdout, &stderr); status != 2 {
+			t.Errorf("runStats(%q) status = %d, want 2", args, status)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// maxLogLineSize is the largest jsonl record accepted from logcli
+const maxLogLineSize = 16 * 1024 * 1024
+
+// streamLogCLIQuery runs a logcli query built by buildLogCLIArgs in jsonl mode
+// and calls fn for every entry as soon as logcli prints it
+func streamLogCLIQuery(args []string, fn func(LogEntry) error) error {
+	cmdArgs := append([]string{}, args[1:]...)
+	cmdArgs = append(cmdArgs, "--output=jsonl", "--quiet")
+
+	// Keep a copy of stderr to classify failures
+	var stderr bytes.Buffer
+	cmd := exec.Command(args[0], cmdArgs...)
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open logcli output: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return newLogCLIError("query", err, "")
+	}
+
+	scanErr := scanLogEntries(stdout, fn)
+	if scanErr != nil {
+		// Stop logcli early, nobody is reading its output anymore
+		_ = cmd.Process.Kill()
+	}
+
+	waitErr := cmd.Wait()
+	if scanErr != nil {
+		return scanErr
+	}
+	if waitErr != nil {
+		return newLogCLIError("query", waitErr, stderr.String())
+	}
+
+	return nil
+}
+
+// scanLogEntries decodes jsonl records from r and calls fn for each of them
+func scanLogEntries(r io.Reader, fn func(LogEntry) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLogLineSize)
+
+	for scanner.Scan() {
+		data := scanner.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+
+		var entry LogEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return fmt.Errorf("failed to parse logcli output: %w", err)
+		}
+
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read logcli output: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// tailDiscoveryArgs is the time range used for label discovery in tail mode,
+// since 'logcli labels' has no notion of following new data
+var tailDiscoveryArgs = []string{"--since", "1h"}
+
+// Reconnect backoff bounds used when the tail stream is lost
+const (
+	tailInitialBackoff = time.Second
+	tailMaxBackoff     = 30 * time.Second
+)
+
+// tailArgs returns the logcli query arguments for following new lines
+func tailArgs(delayFor int) []string {
+	args := []string{"--tail"}
+	if delayFor > 0 {
+		args = append(args, "--delay-for", strconv.Itoa(delayFor))
+	}
+	return args
+}
+
+// resumeArgs appends a start time just after the last received entry,
+// so that a reconnected tail does not repeat lines already shown
+func resumeArgs(args []string, last time.Time) []string {
+	if last.IsZero() {
+		return args
+	}
+	resumed := append([]string{}, args...)
+	return append(resumed, "--from", last.Add(time.Nanosecond).Format(time.RFC3339Nano))
+}
+
+// tailQuery follows a query built with tailArgs and reconnects with backoff
+// whenever logcli exits, until the user interrupts loqui. Failures that a
+// reconnect cannot fix, like missing credentials or an invalid query, end it.
+func tailQuery(args []string, renderer *Renderer) error {
+	var last time.Time
+	backoff := tailInitialBackoff
+
+	for {
+		received := false
+		err := streamLogCLIQuery(resumeArgs(args, last), func(e LogEntry) error {
+			received = true
+			if e.Timestamp.After(last) {
+				last = e.Timestamp
+			}
+			if err := renderer.Render(e); err != nil {
+				return err
+			}
+			return renderer.Flush()
+		})
+
+		if received {
+			backoff = tailInitialBackoff
+		}
+
+		if err != nil {
+			if isPermanentTailError(err) {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Tail interrupted: %v\n", err)
+		}
+		fmt.Fprintf(os.Stderr, "Reconnecting in %s...\n", backoff)
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if backoff > tailMaxBackoff {
+			backoff = tailMaxBackoff
+		}
+	}
+}
+
+// permanentTailErrors are the failures reconnecting cannot fix
+var permanentTailErrors = []error{
+	ErrLogCLINotInstalled,
+	ErrUnauthorized,
+	ErrForbidden,
+	ErrTenantMissing,
+	ErrInvalidQuery,
+}
+
+// isPermanentTailError reports whether err should end the tail rather than
+// reconnect. Every logcli failure is retried unless its cause is one of the
+// permanent ones; errors from loqui itself, like a closed output, end it.
+func isPermanentTailError(err error) bool {
+	var logcliErr *LogCLIError
+	if !errors.As(err, &logcliErr) {
+		return true
+	}
+	for _, kind := range permanentTailErrors {
+		if errors.Is(logcliErr.Kind, kind) {
+			return true
+		}
+	}
+	return false
+}
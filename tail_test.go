@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTailArgs(t *testing.T) {
+	if got, want := tailArgs(0), []string{"--tail"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tailArgs(0) = %v, want %v", got, want)
+	}
+	if got, want := tailArgs(5), []string{"--tail", "--delay-for", "5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tailArgs(5) = %v, want %v", got, want)
+	}
+}
+
+func TestResumeArgs(t *testing.T) {
+	args := []string{"logcli", "query", `{app="nginx"}`, "--tail"}
+
+	if got := resumeArgs(args, time.Time{}); !reflect.DeepEqual(got, args) {
+		t.Errorf("resumeArgs() without entries = %v, want %v", got, args)
+	}
+
+	last := time.Date(2025, 8, 14, 9, 0, 0, 0, time.UTC)
+	want := []string{"logcli", "query", `{app="nginx"}`, "--tail", "--from", "2025-08-14T09:00:00.000000001Z"}
+	if got := resumeArgs(args, last); !reflect.DeepEqual(got, want) {
+		t.Errorf("resumeArgs() = %v, want %v", got, want)
+	}
+	if len(args) != 4 {
+		t.Errorf("resumeArgs() modified its input: %v", args)
+	}
+}
+
+func TestTailQueryStopsOnPermanentFailure(t *testing.T) {
+	logcli, calls := fakeLogCLI(t, `echo '{"timestamp":"2025-08-14T09:00:00Z","labels":{"app":"nginx"},"line":"started"}'
+echo 'Error response from server: 401 Unauthorized' >&2
+exit 1`)
+	invalid, _ := fakeLogCLI(t, `echo 'Error response from server: parse error at line 1, col 2: syntax error (400 Bad Request)' >&2
+exit 1`)
+
+	tests := []struct {
+		name    string
+		logcli  string
+		wantErr error
+	}{
+		{name: "unauthorized", logcli: logcli, wantErr: ErrUnauthorized},
+		{name: "logcli not installed", logcli: filepath.Join(t.TempDir(), "logcli"), wantErr: ErrLogCLINotInstalled},
+		{name: "invalid query", logcli: invalid, wantErr: ErrInvalidQuery},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			renderer, err := newRenderer(io.Discard, FormatRaw, true, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// A retried failure would keep tailQuery sleeping and reconnecting
+			done := make(chan error, 1)
+			go func() {
+				done <- tailQuery([]string{tt.logcli, "query", `{app="nginx"}`, "--tail"}, renderer)
+			}()
+			select {
+			case err := <-done:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("tailQuery() error = %v, want %v", err, tt.wantErr)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("tailQuery() kept reconnecting after a permanent failure")
+			}
+		})
+	}
+
+	if got := readCalls(t, calls); len(got) != 1 {
+		t.Errorf("logcli called %d times, want 1", len(got))
+	}
+}
+
+func TestTailQueryReconnectsOnUnknownFailure(t *testing.T) {
+	// The first run fails without a known cause, the second with a permanent one
+	logcli, calls := fakeLogCLI(t, `if [ "$(wc -l < "$(dirname "$0")/calls")" -lt 2 ]; then
+  echo '{"timestamp":"2025-08-14T09:00:00Z","labels":{"app":"nginx"},"line":"started"}'
+  exit 1
+fi
+echo '403 Forbidden' >&2
+exit 1`)
+	renderer, err := newRenderer(io.Discard, FormatRaw, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tailQuery([]string{logcli, "query", `{app="nginx"}`, "--tail"}, renderer)
+	if !errors.Is(err, ErrForbidden) {
+		t.Errorf("tailQuery() error = %v, want %v", err, ErrForbidden)
+	}
+	got := readCalls(t, calls)
+	if len(got) != 2 || !strings.HasSuffix(got[1], "--from 2025-08-14T09:00:00.000000001Z --output=jsonl --quiet") {
+		t.Errorf("logcli called with %v, want a resumed second call", got)
+	}
+}
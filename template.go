@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// labelNamePattern matches valid Loki label names
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// lokiTemplateFuncs lists the functions Loki offers in line_format and
+// label_format templates. The common ones are implemented for previews,
+// the others only need to exist for templates to parse.
+var lokiTemplateFuncs = template.FuncMap{
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"ToLower":    strings.ToLower,
+	"ToUpper":    strings.ToUpper,
+	"title":      titleCase,
+	"trim":       strings.TrimSpace,
+	"TrimSpace":  strings.TrimSpace,
+	"trimAll":    func(cutset, s string) string { return strings.Trim(s, cutset) },
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"TrimPrefix": strings.TrimPrefix,
+	"TrimSuffix": strings.TrimSuffix,
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"Replace":    strings.Replace,
+	"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"repeat":     func(n int, s string) string { return strings.Repeat(s, n) },
+	"default": func(def string, value ...string) string {
+		if len(value) == 0 || value[0] == "" {
+			return def
+		}
+		return value[0]
+	},
+	"trunc": func(n int, s string) string {
+		if n >= 0 && n < len(s) {
+			return s[:n]
+		}
+		if n < 0 && -n < len(s) {
+			return s[len(s)+n:]
+		}
+		return s
+	},
+	"substr": func(start, end int, s string) string {
+		if start < 0 || start > len(s) {
+			return ""
+		}
+		if end < 0 || end > len(s) {
+			end = len(s)
+		}
+		if end < start {
+			return ""
+		}
+		return s[start:end]
+	},
+	"alignLeft": func(n int, s string) string {
+		if len(s) >= n {
+			return s[:n]
+		}
+		return s + strings.Repeat(" ", n-len(s))
+	},
+	"alignRight": func(n int, s string) string {
+		if len(s) >= n {
+			return s[len(s)-n:]
+		}
+		return strings.Repeat(" ", n-len(s)) + s
+	},
+	"regexReplaceAll": func(pattern, s, repl string) (string, error) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", err
+		}
+		return re.ReplaceAllString(s, repl), nil
+	},
+	"regexReplaceAllLiteral": func(pattern, s, repl string) (string, error) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", err
+		}
+		return re.ReplaceAllLiteralString(s, repl), nil
+	},
+
+	// Evaluated by Loki only, previews show their last argument
+	"__line__":         unpreviewed,
+	"__timestamp__":    unpreviewed,
+	"b64enc":           unpreviewed,
+	"b64dec":           unpreviewed,
+	"bytes":            unpreviewed,
+	"count":            unpreviewed,
+	"date":             unpreviewed,
+	"duration":         unpreviewed,
+	"duration_seconds": unpreviewed,
+	"fromJson":         unpreviewed,
+	"indent":           unpreviewed,
+	"nindent":          unpreviewed,
+	"now":              unpreviewed,
+	"toDate":           unpreviewed,
+	"toDateInZone":     unpreviewed,
+	"unixEpoch":        unpreviewed,
+	"unixEpochMillis":  unpreviewed,
+	"unixEpochNanos":   unpreviewed,
+	"unixToTime":       unpreviewed,
+	"urlencode":        unpreviewed,
+	"urldecode":        unpreviewed,
+	"add":              unpreviewed,
+	"sub":              unpreviewed,
+	"mul":              unpreviewed,
+	"div":              unpreviewed,
+	"mod":              unpreviewed,
+	"addf":             unpreviewed,
+	"subf":             unpreviewed,
+	"mulf":             unpreviewed,
+	"divf":             unpreviewed,
+	"max":              unpreviewed,
+	"min":              unpreviewed,
+	"maxf":             unpreviewed,
+	"minf":             unpreviewed,
+	"ceil":             unpreviewed,
+	"floor":            unpreviewed,
+	"round":            unpreviewed,
+}
+
+// unpreviewed stands in for template functions loqui does not evaluate
+func unpreviewed(args ...any) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return fmt.Sprint(args[len(args)-1])
+}
+
+// titleCase upper-cases the first letter of every word like the title
+// function of Loki, which is the deprecated strings.Title, so previews match
+func titleCase(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	prev := ' '
+	for _, r := range s {
+		if isWordSeparator(prev) {
+			r = unicode.ToTitle(r)
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return b.String()
+}
+
+// isWordSeparator reports whether r separates words for titleCase: ASCII
+// characters other than letters, digits and underscores, and spaces
+func isWordSeparator(r rune) bool {
+	if r <= unicode.MaxASCII {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}
+	return unicode.IsSpace(r)
+}
+
+// parseLokiTemplate parses a line_format or label_format template
+// __line__ and __timestamp__ are bound to the given log entry
+func parseLokiTemplate(text string, line string, ts time.Time) (*template.Template, error) {
+	// Missing labels render empty, as in Loki
+	return template.New("").Option("missingkey=zero").Funcs(lokiTemplateFuncs).Funcs(template.FuncMap{
+		"__line__":      func() string { return line },
+		"__timestamp__": func() time.Time { return ts },
+	}).Parse(text)
+}
+
+// validateTemplate checks the template syntax of a line_format or label_format template
+func validateTemplate(text string) error {
+	if _, err := parseLokiTemplate(text, "", time.Time{}); err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	return nil
+}
+
+// renderTemplate executes a template against the labels of a sample line
+func renderTemplate(text string, labels map[string]string, line string, ts time.Time) (string, error) {
+	tmpl, err := parseLokiTemplate(text, line, ts)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, labels); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// LabelFormat renames a label (dst=src) or sets it from a template (dst="{{...}}")
+type LabelFormat struct {
+	Dst      string
+	Src      string
+	Template bool
+}
+
+func (f LabelFormat) String() string {
+	if f.Template {
+		return f.Dst + "=" + logqlString(f.Src)
+	}
+	return f.Dst + "=" + f.Src
+}
+
+// parseLabelFormats parses comma separated label_format assignments like
+// `service=app, summary="{{.level}}: {{.msg}}"`
+func parseLabelFormats(input string) ([]LabelFormat, error) {
+	formats := []LabelFormat{}
+	for _, part := range splitOutsideQuotes(input, ',') {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		dst, src, ok := strings.Cut(part, "=")
+		dst, src = strings.TrimSpace(dst), strings.TrimSpace(src)
+		if !ok || dst == "" || src == "" {
+			return nil, fmt.Errorf("invalid label format %q (expected new=old or new=\"{{template}}\")", part)
+		}
+		if !labelNamePattern.MatchString(dst) {
+			return nil, fmt.Errorf("invalid label name: %s", dst)
+		}
+
+		format := LabelFormat{Dst: dst, Src: src}
+		if quote := src[0]; quote == '"' || quote == '`' {
+			if len(src) < 2 || src[len(src)-1] != quote {
+				return nil, fmt.Errorf("unterminated template in %q", part)
+			}
+			format.Src = src[1 : len(src)-1]
+			if quote == '"' {
+				unquoted, err := strconv.Unquote(src)
+				if err != nil {
+					return nil, fmt.Errorf("invalid template string in %q: %w", part, err)
+				}
+				format.Src = unquoted
+			}
+			format.Template = true
+			if err := validateTemplate(format.Src); err != nil {
+				return nil, err
+			}
+		} else if !labelNamePattern.MatchString(src) {
+			return nil, fmt.Errorf("invalid label name: %s", src)
+		}
+
+		formats = append(formats, format)
+	}
+
+	if len(formats) == 0 {
+		return nil, fmt.Errorf("no label format given")
+	}
+	return formats, nil
+}
+
+// splitOutsideQuotes splits s at sep, except inside "..." and `...`
+func splitOutsideQuotes(s string, sep rune) []string {
+	parts := []string{}
+	var quote rune
+	escaped := false
+	start := 0
+	for i, c := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && c == '\\':
+			escaped = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '`':
+			quote = c
+		case c == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// applyLabelFormats returns the labels after the label_format stage
+func applyLabelFormats(formats []LabelFormat, labels map[string]string, line string, ts time.Time) (map[string]string, error) {
+	result := make(map[string]string, len(labels))
+	for k, v := range labels {
+		result[k] = v
+	}
+
+	// All assignments of one stage see the labels from before the stage
+	for _, f := range formats {
+		if !f.Template {
+			result[f.Dst] = labels[f.Src]
+			if f.Dst != f.Src {
+				delete(result, f.Src)
+			}
+			continue
+		}
+		value, err := renderTemplate(f.Src, labels, line, ts)
+		if err != nil {
+			return nil, err
+		}
+		result[f.Dst] = value
+	}
+	return result, nil
+}
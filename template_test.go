@@ -0,0 +1,136 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	valid := []string{
+		"{{.level}} {{.msg}}",
+		`{{ .msg | lower | trunc 20 }}`,
+		`{{ .user | default "anonymous" }} {{ __line__ }}`,
+		`{{ if eq .level "error" }}!{{ end }}{{ .msg }}`,
+		`{{ .duration | duration_seconds }}`,
+	}
+	for _, text := range valid {
+		if err := validateTemplate(text); err != nil {
+			t.Errorf("validateTemplate(%q) error = %v", text, err)
+		}
+	}
+
+	invalid := []string{"{{.level", "{{ .msg | nosuchfunc }}", "{{ end }}"}
+	for _, text := range invalid {
+		if err := validateTemplate(text); err == nil {
+			t.Errorf("validateTemplate(%q) expected error", text)
+		}
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	labels := map[string]string{"level": "error", "msg": "Connection Refused", "user": ""}
+	ts := time.Date(2025, 8, 14, 1, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"{{.level}} {{.msg}}", "error Connection Refused"},
+		{"{{ .msg | lower }}", "connection refused"},
+		{"{{ .msg | trunc 10 }}", "Connection"},
+		{`{{ .user | default "anonymous" }}`, "anonymous"},
+		{"{{ .missing }}", ""},
+		{"{{ __line__ }}", "raw line"},
+		{`{{ .level | upper | printf "[%s]" }}`, "[ERROR]"},
+		{"{{ .level | title }}", "Error"},
+	}
+
+	for _, tt := range tests {
+		got, err := renderTemplate(tt.text, labels, "raw line", ts)
+		if err != nil {
+			t.Errorf("renderTemplate(%q) error = %v", tt.text, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("renderTemplate(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTitleCase(t *testing.T) {
+	tests := map[string]string{
+		"":                    "",
+		"connection refused":  "Connection Refused",
+		"user-id:42 was here": "User-Id:42 Was Here",
+		"snake_case it's\tok": "Snake_case It'S\tOk",
+		"élan vital":          "Élan Vital",
+	}
+	for input, want := range tests {
+		if got := titleCase(input); got != want {
+			t.Errorf("titleCase(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestParseLabelFormats(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []LabelFormat
+		wantErr bool
+	}{
+		{
+			input: "svc=app",
+			want:  []LabelFormat{{Dst: "svc", Src: "app"}},
+		},
+		{
+			input: `svc=app, summary="{{.level}}, {{.msg}}"`,
+			want: []LabelFormat{
+				{Dst: "svc", Src: "app"},
+				{Dst: "summary", Src: "{{.level}}, {{.msg}}", Template: true},
+			},
+		},
+		{
+			input: `who="{{ .user | default \"anon\" }}"`,
+			want:  []LabelFormat{{Dst: "who", Src: `{{ .user | default "anon" }}`, Template: true}},
+		},
+		{
+			input: "who=`{{ .user | default \"anon\" }}`",
+			want:  []LabelFormat{{Dst: "who", Src: `{{ .user | default "anon" }}`, Template: true}},
+		},
+		{input: "svc", wantErr: true},
+		{input: "1svc=app", wantErr: true},
+		{input: "svc=app-name", wantErr: true},
+		{input: `svc="{{.app"`, wantErr: true},
+		{input: `svc="{{.app}}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseLabelFormats(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLabelFormats() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLabelFormats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyLabelFormats(t *testing.T) {
+	labels := map[string]string{"app": "nginx", "level": "error"}
+	formats := []LabelFormat{
+		{Dst: "svc", Src: "app"},
+		{Dst: "summary", Src: "{{.app}}/{{.level}}", Template: true},
+	}
+
+	got, err := applyLabelFormats(formats, labels, "line", time.Time{})
+	if err != nil {
+		t.Fatalf("applyLabelFormats() error = %v", err)
+	}
+	want := map[string]string{"svc": "nginx", "level": "error", "summary": "nginx/error"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("applyLabelFormats() = %v, want %v", got, want)
+	}
+}
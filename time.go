@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -34,3 +35,65 @@ func convertToRFC3339(input string, isStart bool) (string, error) {
 
 	return "", fmt.Errorf("invalid time format: %s (expected YYYY-MM-DD HH:MM or YYYY-MM-DD)", input)
 }
+
+// parseSince parses a relative duration as used with --since
+// In addition to Go durations it accepts whole days (7d) and weeks (2w)
+func parseSince(input string) (time.Duration, error) {
+	input = strings.TrimSpace(input)
+
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(input, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 1 {
+				return 0, fmt.Errorf("invalid duration: %s", input)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(input)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration: %s (expected e.g. 30m, 24h, 7d)", input)
+	}
+	return d, nil
+}
+
+// resolveTimeRange converts time range arguments produced by selectTimeRange
+// into absolute start and end times, using now for relative ranges
+func resolveTimeRange(timeArgs []string, now time.Time) (time.Time, time.Time, error) {
+	var start, end time.Time
+	end = now
+
+	for i := 0; i+1 < len(timeArgs); i += 2 {
+		value := timeArgs[i+1]
+		switch timeArgs[i] {
+		case "--since":
+			d, err := parseSince(value)
+			if err != nil {
+				return time.Time{}, time.Time{}, err
+			}
+			start = now.Add(-d)
+		case "--from":
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return time.Time{}, time.Time{}, fmt.Errorf("invalid start time: %w", err)
+			}
+			start = t
+		case "--to":
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return time.Time{}, time.Time{}, fmt.Errorf("invalid end time: %w", err)
+			}
+			end = t
+		}
+	}
+
+	if start.IsZero() {
+		return time.Time{}, time.Time{}, fmt.Errorf("no start time in %v", timeArgs)
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start time %s is not before end time %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+
+	return start, end, nil
+}
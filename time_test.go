@@ -101,3 +101,82 @@ func TestConvertToRFC3339(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSince(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{input: "30m", want: 30 * time.Minute},
+		{input: "24h", want: 24 * time.Hour},
+		{input: "7d", want: 7 * 24 * time.Hour},
+		{input: "2w", want: 14 * 24 * time.Hour},
+		{input: " 1h ", want: time.Hour},
+		{input: "0d", wantErr: true},
+		{input: "-1h", wantErr: true},
+		{input: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseSince(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSince(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseSince(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveTimeRange(t *testing.T) {
+	now := time.Date(2025, 8, 14, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		timeArgs  []string
+		wantStart time.Time
+		wantEnd   time.Time
+		wantErr   bool
+	}{
+		{
+			name:      "relative",
+			timeArgs:  []string{"--since", "2h"},
+			wantStart: now.Add(-2 * time.Hour),
+			wantEnd:   now,
+		},
+		{
+			name:      "absolute",
+			timeArgs:  []string{"--from", "2025-08-14T09:00:00+09:00", "--to", "2025-08-14T18:00:00+09:00"},
+			wantStart: time.Date(2025, 8, 14, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2025, 8, 14, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "end before start",
+			timeArgs: []string{"--from", "2025-08-14T18:00:00+09:00", "--to", "2025-08-14T09:00:00+09:00"},
+			wantErr:  true,
+		},
+		{
+			name:     "no range",
+			timeArgs: []string{},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := resolveTimeRange(tt.timeArgs, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveTimeRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("resolveTimeRange() = %v, %v, want %v, %v", start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
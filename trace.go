@@ -0,0 +1,271 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults of loqui trace, used when neither flags nor the configuration file set them
+const (
+	// Loki 3 adds service_name to every stream, so matching any value of it
+	// selects all streams without a full scan of the index
+	defaultTraceLabel  = "service_name"
+	defaultTraceWindow = "1h"
+	defaultTraceKey    = "trace_id"
+	defaultTraceLimit  = 5000
+)
+
+// Ways loqui trace matches the ID
+const (
+	TraceMatchLine     = "line"     // Lines containing the ID
+	TraceMatchMetadata = "metadata" // Lines carrying the ID as structured metadata
+	TraceMatchBoth     = "both"
+)
+
+// parseTraceLabels turns label names into matchers for any value, and parses
+// matchers like env="prod" as given
+func parseTraceLabels(items []string) ([]LabelSelector, error) {
+	selectors := []LabelSelector{}
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if labelNamePattern.MatchString(item) {
+			selectors = append(selectors, LabelSelector{Label: item, Operator: "=~", Value: ".+"})
+			continue
+		}
+
+		matchers, err := parseConditions(item)
+		if err != nil || len(matchers) != 1 {
+			return nil, fmt.Errorf("invalid trace label %q (expected a label name or a matcher like env=\"prod\")", item)
+		}
+		selectors = append(selectors, matchers[0])
+	}
+
+	if len(selectors) == 0 {
+		return nil, fmt.Errorf("no trace labels given")
+	}
+	return selectors, nil
+}
+
+// traceQueries returns the queries finding id: one for lines containing it
+// and one for lines carrying it in the structured metadata key. LogQL cannot
+// combine both in a single query.
+func traceQueries(selectors []LabelSelector, id, key, match string) []Query {
+	queries := []Query{}
+	if match == TraceMatchLine || match == TraceMatchBoth {
+		queries = append(queries, Query{
+			Selectors:   selectors,
+			LineFilters: []LineFilter{{Operator: "|=", Text: id}},
+		})
+	}
+	if match == TraceMatchMetadata || match == TraceMatchBoth {
+		queries = append(queries, Query{
+			Selectors: selectors,
+			Metadata:  []LabelSelector{{Label: key, Operator: "=", Value: id}},
+		})
+	}
+	return queries
+}
+
+// fetchTrace runs the queries and returns their entries oldest first across
+// all streams, without the lines both queries found, and the number of
+// lines each query returned
+func fetchTrace(logcliCmd string, queries []Query, timeArgs []string) ([]LogEntry, []int, error) {
+	entries := []LogEntry{}
+	counts := make([]int, len(queries))
+	seen := map[string]bool{}
+	for i, query := range queries {
+		args := buildLogCLIArgs(logcliCmd, query, timeArgs)
+		err := streamLogCLIQuery(args, func(e LogEntry) error {
+			counts[i]++
+			key := e.Timestamp.Format(time.RFC3339Nano) + " " + formatLabels(e.Labels) + " " + e.Line
+			if !seen[key] {
+				seen[key] = true
+				entries = append(entries, e)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+	return entries, counts, nil
+}
+
+const traceUsage = `Usage:
+  loqui trace [options] <id>
+
+Finds every log line mentioning a trace ID in any stream and prints them
+oldest first. Lines containing the ID and lines carrying it as structured
+metadata are both matched by default.
+
+Options:
+  -config      Configuration file
+               (default: ~/.config/loqui/config.json)
+  -labels      Stream selector, comma separated label names matched with
+               =~".+" or matchers like env="prod"
+               (default: trace.labels in the config file, or service_name)
+  -window      How far back to search, e.g. 30m, 6h or 2d
+               (default: trace.window in the config file, or 1h)
+  -key         Structured metadata key holding the ID
+               (default: trace.metadata_key in the config file, or trace_id)
+  -match       line, metadata or both
+               (default: trace.match in the config file, or both)
+  -limit       Maximum number of lines per query, 0 for all (default: 5000)
+  -format      Output format: default, raw, jsonl, csv, color
+               (default: default)
+  -utc         Show timestamps in UTC instead of local time
+`
+
+// runTrace implements the trace subcommand and returns the exit status
+func runTrace(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("trace", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, traceUsage)
+	}
+	configPath := flags.String("config", "", "Configuration file")
+	labels := flags.String("labels", "", "Stream selector labels")
+	window := flags.String("window", "", "How far back to search")
+	key := flags.String("key", "", "Structured metadata key holding the ID")
+	match := flags.String("match", "", "line, metadata or both")
+	limit := flags.Int("limit", defaultTraceLimit, "Maximum number of lines per query")
+	format := flags.String("format", FormatDefault, "Output format")
+	utc := flags.Bool("utc", false, "Show timestamps in UTC")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if flags.NArg() != 1 || strings.TrimSpace(flags.Arg(0)) == "" {
+		fmt.Fprint(stderr, traceUsage)
+		return 2
+	}
+	id := strings.TrimSpace(flags.Arg(0))
+
+	if err := validateFormat(*format); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	fileConfig, err := loadFileConfig(configFile(*configPath), *configPath != "")
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	// Flags win over the config file, which wins over the defaults
+	labelItems := fileConfig.Trace.Labels
+	if *labels != "" {
+		labelItems = splitOutsideQuotes(*labels, ',')
+	}
+	if len(labelItems) == 0 {
+		labelItems = []string{defaultTraceLabel}
+	}
+	selectors, err := parseTraceLabels(labelItems)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	*window = firstNonEmpty(*window, fileConfig.Trace.Window, defaultTraceWindow)
+	since, err := parseSince(*window)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: invalid window: %v\n", err)
+		return 2
+	}
+
+	*key = firstNonEmpty(*key, fileConfig.Trace.MetadataKey, defaultTraceKey)
+	if !labelNamePattern.MatchString(*key) {
+		fmt.Fprintf(stderr, "Error: invalid metadata key: %s\n", *key)
+		return 2
+	}
+
+	*match = firstNonEmpty(*match, fileConfig.Trace.Match, TraceMatchBoth)
+	if *match != TraceMatchLine && *match != TraceMatchMetadata && *match != TraceMatchBoth {
+		fmt.Fprintf(stderr, "Error: invalid match: %s (expected line, metadata or both)\n", *match)
+		return 2
+	}
+
+	if *limit < 0 {
+		fmt.Fprintf(stderr, "Error: invalid limit: %d\n", *limit)
+		return 2
+	}
+
+	if os.Getenv("LOKI_ADDR") == "" {
+		fmt.Fprintf(stderr, "Error: LOKI_ADDR environment variable is not set\n")
+		return 2
+	}
+
+	end := time.Now()
+	timeArgs := []string{
+		"--from", end.Add(-since).Format(time.RFC3339),
+		"--to", end.Format(time.RFC3339),
+		"--limit", strconv.Itoa(*limit),
+		"--forward",
+	}
+	queries := traceQueries(selectors, id, *key, *match)
+	for _, query := range queries {
+		fmt.Fprintf(stderr, "Searching %s\n", query)
+	}
+
+	entries, counts, err := fetchTrace("logcli", queries, timeArgs)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		if hint := errorHint(err); hint != "" {
+			fmt.Fprintf(stderr, "Hint: %s\n", hint)
+		}
+		return 1
+	}
+
+	// Queries run oldest first, a full result is missing the newest lines
+	for i, query := range queries {
+		if *limit > 0 && counts[i] == *limit {
+			fmt.Fprintf(stderr, "Warning: %s returned %d lines, the limit; newer lines are missing (raise -limit or narrow -window)\n", query, *limit)
+		}
+	}
+
+	renderer, err := newRenderer(stdout, *format, *utc, []LineFilter{{Operator: "|=", Text: id}})
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	for _, e := range entries {
+		if err := renderer.Render(e); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	if err := renderer.Flush(); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(stderr, "Found %d lines for %s in the last %s\n", len(entries), id, *window)
+	return 0
+}
+
+// firstNonEmpty returns the first of values that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTraceLabels(t *testing.T) {
+	got, err := parseTraceLabels([]string{"namespace", ` env="prod" `, ""})
+	if err != nil {
+		t.Fatalf("parseTraceLabels() error = %v", err)
+	}
+	want := []LabelSelector{
+		{Label: "namespace", Operator: "=~", Value: ".+"},
+		{Label: "env", Operator: "=", Value: "prod"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTraceLabels() = %v, want %v", got, want)
+	}
+
+	for _, items := range [][]string{nil, {"env=prod"}, {`env="prod", app="api"`}, {"not a label"}} {
+		if _, err := parseTraceLabels(items); err == nil {
+			t.Errorf("parseTraceLabels(%q) expected error", items)
+		}
+	}
+}
+
+func TestTraceQueries(t *testing.T) {
+	selectors := []LabelSelector{{Label: "service_name", Operator: "=~", Value: ".+"}}
+
+	tests := []struct {
+		match string
+		want  []string
+	}{
+		{TraceMatchLine, []string{`{service_name=~".+"} |= "abc"`}},
+		{TraceMatchMetadata, []string{`{service_name=~".+"} | trace_id="abc"`}},
+		{TraceMatchBoth, []string{`{service_name=~".+"} |= "abc"`, `{service_name=~".+"} | trace_id="abc"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.match, func(t *testing.T) {
+			got := []string{}
+			for _, q := range traceQueries(selectors, "abc", "trace_id", tt.match) {
+				got = append(got, q.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("traceQueries() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchTrace(t *testing.T) {
+	// The line query and the metadata query both find the checkout line
+	logcli, calls := fakeLogCLI(t, `case "$2" in
+*'|='*)
+  echo '{"timestamp":"2025-08-14T01:00:02Z","labels":{"service_name":"checkout"},"line":"paid abc"}'
+  echo '{"timestamp":"2025-08-14T01:00:00Z","labels":{"service_name":"gateway"},"line":"GET /pay abc"}' ;;
+*)
+  echo '{"timestamp":"2025-08-14T01:00:01Z","labels":{"service_name":"payments"},"line":"charged"}'
+  echo '{"timestamp":"2025-08-14T01:00:02Z","labels":{"service_name":"checkout"},"line":"paid abc"}' ;;
+esac`)
+
+	queries := traceQueries([]LabelSelector{{Label: "service_name", Operator: "=~", Value: ".+"}}, "abc", "trace_id", TraceMatchBoth)
+	entries, counts, err := fetchTrace(logcli, queries, []string{"--since", "1h", "--forward"})
+	if err != nil {
+		t.Fatalf("fetchTrace() error = %v", err)
+	}
+
+	got := []string{}
+	for _, e := range entries {
+		got = append(got, e.Labels["service_name"])
+	}
+	want := []string{"gateway", "payments", "checkout"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fetchTrace() services = %v, want %v", got, want)
+	}
+	if want := []int{2, 2}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("fetchTrace() counts = %v, want %v", counts, want)
+	}
+	if n := len(readCalls(t, calls)); n != 2 {
+		t.Errorf("logcli called %d times, want 2", n)
+	}
+}
+
+func TestRunTraceInvalidArguments(t *testing.T) {
+	t.Setenv("LOKI_ADDR", "http://localhost:3100")
+
+	for _, args := range [][]string{
+		{},
+		{"-match", "stream", "abc"},
+		{"-window", "soon", "abc"},
+		{"-key", "trace-id", "abc"},
+		{"-labels", "env=prod", "abc"},
+		{"-limit", "-1", "abc"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if status := runTrace(args, &stdout, &stderr); status != 2 {
+			t.Errorf("runTrace(%q) status = %d, want 2", args, status)
+		}
+	}
+}
+
+func TestRunTraceWarnsAtLimit(t *testing.T) {
+	// Only the line query fills the limit
+	logcli, _ := fakeLogCLI(t, `case "$2" in
+*'|='*)
+  echo '{"timestamp":"2025-08-14T01:00:00Z","labels":{"service_name":"gateway"},"line":"GET /pay abc"}'
+  echo '{"timestamp":"2025-08-14T01:00:01Z","labels":{"service_name":"checkout"},"line":"paid abc"}' ;;
+*)
+  echo '{"timestamp":"2025-08-14T01:00:01Z","labels":{"service_name":"checkout"},"line":"paid abc"}' ;;
+esac`)
+	t.Setenv("PATH", filepath.Dir(logcli))
+	t.Setenv("LOKI_ADDR", "http://localhost:3100")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	var stdout, stderr bytes.Buffer
+	if status := runTrace([]string{"-limit", "2", "abc"}, &stdout, &stderr); status != 0 {
+		t.Fatalf("runTrace() status = %d, stderr %s", status, stderr.String())
+	}
+	if n := strings.Count(stderr.String(), "Warning:"); n != 1 || !strings.Contains(stderr.String(), `|= "abc" returned 2 lines`) {
+		t.Errorf("runTrace() stderr = %s, want one limit warning for the line query", stderr.String())
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// UsageStats counts how often labels and label values were used in queries,
+// to rank them in the selector. A nil UsageStats records nothing.
+type UsageStats struct {
+	Labels map[string]int            `json:"labels"`
+	Values map[string]map[string]int `json:"values"`
+
+	path string
+}
+
+// defaultUsagePath returns $XDG_STATE_HOME/loqui/usage.json, falling back to
+// ~/.local/state/loqui/usage.json
+func defaultUsagePath() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "loqui", "usage.json")
+}
+
+// loadUsageStats reads the stats file at path; a missing file means no usage yet
+func loadUsageStats(path string) (*UsageStats, error) {
+	stats := &UsageStats{
+		Labels: map[string]int{},
+		Values: map[string]map[string]int{},
+		path:   path,
+	}
+	if path == "" {
+		return stats, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return stats, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, stats); err != nil {
+		return nil, err
+	}
+	if stats.Labels == nil {
+		stats.Labels = map[string]int{}
+	}
+	if stats.Values == nil {
+		stats.Values = map[string]map[string]int{}
+	}
+	return stats, nil
+}
+
+// Record counts the labels and exact values used by selectors
+func (u *UsageStats) Record(selectors []LabelSelector) {
+	if u == nil {
+		return
+	}
+
+	for _, s := range selectors {
+		u.Labels[s.Label]++
+
+		// Regex patterns are not values that can be offered later
+		if s.Operator == "=" || s.Operator == "!=" {
+			if u.Values[s.Label] == nil {
+				u.Values[s.Label] = map[string]int{}
+			}
+			u.Values[s.Label][s.Value]++
+		}
+	}
+}
+
+// Save writes the stats file
+func (u *UsageStats) Save() error {
+	if u == nil || u.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(u.path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(u.path, data, 0o600)
+}
+
+// LabelCounts returns how often each label was used
+func (u *UsageStats) LabelCounts() map[string]int {
+	if u == nil {
+		return nil
+	}
+	return u.Labels
+}
+
+// ValueCounts returns how often each value of label was used
+func (u *UsageStats) ValueCounts(label string) map[string]int {
+	if u == nil {
+		return nil
+	}
+	return u.Values[label]
+}
+
+// TopLabels returns up to n labels, most used first
+func (u *UsageStats) TopLabels(n int) []string {
+	counts := u.LabelCounts()
+	labels := make([]string, 0, len(counts))
+	for label := range counts {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if counts[labels[i]] != counts[labels[j]] {
+			return counts[labels[i]] > counts[labels[j]]
+		}
+		return labels[i] < labels[j]
+	})
+
+	if len(labels) > n {
+		labels = labels[:n]
+	}
+	return labels
+}
+
+// rankItems orders items for the selector: favorites first in the configured
+// order, then items by descending usage count, then the rest as given
+func rankItems(items []string, favorites []string, counts map[string]int) []string {
+	present := make(map[string]bool, len(items))
+	for _, item := range items {
+		present[item] = true
+	}
+
+	ranked := make([]string, 0, len(items))
+	pinned := make(map[string]bool)
+	for _, fav := range favorites {
+		if present[fav] && !pinned[fav] {
+			ranked = append(ranked, fav)
+			pinned[fav] = true
+		}
+	}
+
+	rest := make([]string, 0, len(items))
+	for _, item := range items {
+		if !pinned[item] {
+			rest = append(rest, item)
+		}
+	}
+	sort.SliceStable(rest, func(i, j int) bool {
+		return counts[rest[i]] > counts[rest[j]]
+	})
+
+	return append(ranked, rest...)
+}
+
+// hideItems removes hidden items from items
+func hideItems(items []string, hidden []string) []string {
+	if len(hidden) == 0 {
+		return items
+	}
+
+	skip := make(map[string]bool, len(hidden))
+	for _, h := range hidden {
+		skip[h] = true
+	}
+
+	visible := make([]string, 0, len(items))
+	for _, item := range items {
+		if !skip[item] {
+			visible = append(visible, item)
+		}
+	}
+	return visible
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRankItems(t *testing.T) {
+	tests := []struct {
+		name      string
+		items     []string
+		favorites []string
+		counts    map[string]int
+		want      []string
+	}{
+		{
+			name:  "no ranking keeps order",
+			items: []string{"app", "env", "job"},
+			want:  []string{"app", "env", "job"},
+		},
+		{
+			name:   "most used first",
+			items:  []string{"app", "env", "job", "pod"},
+			counts: map[string]int{"job": 2, "pod": 5},
+			want:   []string{"pod", "job", "app", "env"},
+		},
+		{
+			name:      "favorites pinned above usage",
+			items:     []string{"app", "env", "job", "namespace"},
+			favorites: []string{"namespace", "missing", "app"},
+			counts:    map[string]int{"job": 9},
+			want:      []string{"namespace", "app", "job", "env"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rankItems(tt.items, tt.favorites, tt.counts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rankItems() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHideItems(t *testing.T) {
+	got := hideItems([]string{"__stream_shard__", "app", "filename"}, []string{"filename", "__stream_shard__"})
+	if want := []string{"app"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("hideItems() = %v, want %v", got, want)
+	}
+}
+
+func TestUsageStatsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loqui", "usage.json")
+
+	stats, err := loadUsageStats(path)
+	if err != nil {
+		t.Fatalf("loadUsageStats() error = %v", err)
+	}
+	stats.Record([]LabelSelector{
+		{Label: "app", Operator: "=", Value: "nginx"},
+		{Label: "env", Operator: "=~", Value: "prod.*"},
+	})
+	stats.Record([]LabelSelector{{Label: "app", Operator: "=", Value: "nginx"}})
+	if err := stats.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := loadUsageStats(path)
+	if err != nil {
+		t.Fatalf("loadUsageStats() error = %v", err)
+	}
+	if got := loaded.LabelCounts(); got["app"] != 2 || got["env"] != 1 {
+		t.Errorf("LabelCounts() = %v", got)
+	}
+	if got := loaded.ValueCounts("app")["nginx"]; got != 2 {
+		t.Errorf("ValueCounts(app)[nginx] = %d, want 2", got)
+	}
+	if got := loaded.ValueCounts("env"); len(got) != 0 {
+		t.Errorf("regex values were recorded: %v", got)
+	}
+	if got, want := loaded.TopLabels(1), []string{"app"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TopLabels(1) = %v, want %v", got, want)
+	}
+
+	var none *UsageStats
+	none.Record([]LabelSelector{{Label: "app", Operator: "=", Value: "x"}})
+	if err := none.Save(); err != nil {
+		t.Errorf("Save() on nil stats error = %v", err)
+	}
+	if got := none.TopLabels(3); len(got) != 0 {
+		t.Errorf("TopLabels() on nil stats = %v", got)
+	}
+}